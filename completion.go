@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/arbourd/git-open/gitw"
+	"github.com/arbourd/git-open/open"
+)
+
+// completions holds the shell completion scripts
+//
+//go:embed completions
+var completions embed.FS
+
+// shells are the shells with a completion script
+var shells = []string{"bash", "fish", "zsh"}
+
+// completionScript returns the completion script for shell
+func completionScript(shell string) (string, error) {
+	if !slices.Contains(shells, shell) {
+		return "", fmt.Errorf("unsupported shell: %q, expected one of: %s", shell, strings.Join(shells, ", "))
+	}
+
+	b, err := completions.ReadFile("completions/git-open." + shell)
+	return string(b), err
+}
+
+// flags are the options suggested when completing a word starting with `-`
+var flags = []string{"--changed", "--explain", "--files", "--help", "--lenient", "--ref", "--remote", "--staged", "--version", "--view"}
+
+// valueFlags are the options that take a value as the following argument
+var valueFlags = []string{"--files", "--ref", "--remote", "--view"}
+
+// commands are the subcommands suggested when completing the first argument
+var commands = []string{"completion", "help"}
+
+// complete returns the completion candidates for the last of words, which is the word being completed.
+// Preceding words are the arguments already on the command line.
+func complete(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur, prev := words[len(words)-1], words[:len(words)-1]
+
+	if slices.Contains(prev, "--") {
+		return completePaths(cur)
+	}
+
+	if len(prev) > 0 {
+		switch prev[len(prev)-1] {
+		case "--files", "--ref":
+			return completeRevisions(cur)
+		case "--remote":
+			remotes, _ := gitw.Remotes(context.Background(), ".")
+			return withPrefix(remotes, cur)
+		case "--view":
+			var views []string
+			for _, v := range open.Views {
+				views = append(views, string(v))
+			}
+			return withPrefix(views, cur)
+		}
+	}
+
+	var args []string
+	for i, w := range prev {
+		switch w {
+		case "--changed", "--staged", "--files", "--version", "--help", "-h":
+			// These options take no targets
+			return nil
+		}
+		if !strings.HasPrefix(w, "-") && (i == 0 || !slices.Contains(valueFlags, prev[i-1])) {
+			args = append(args, w)
+		}
+	}
+	if len(args) > 0 {
+		switch args[0] {
+		case "completion":
+			if len(args) == 1 {
+				return withPrefix(shells, cur)
+			}
+			return nil
+		case "help":
+			return nil
+		}
+	}
+
+	if strings.HasPrefix(cur, "-") {
+		return withPrefix(flags, cur)
+	}
+
+	candidates := completePaths(cur)
+	if len(args) == 0 {
+		candidates = append(withPrefix(commands, cur), candidates...)
+	}
+	return candidates
+}
+
+// completePaths returns the tracked paths starting with cur, completing one directory at a time
+func completePaths(cur string) []string {
+	dir := cur[:strings.LastIndex(cur, "/")+1]
+
+	var pathspecs []string
+	if dir != "" {
+		pathspecs = append(pathspecs, dir)
+	}
+	files, err := gitw.LsFiles(context.Background(), ".", pathspecs...)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	for _, f := range files {
+		if !strings.HasPrefix(f, cur) || !strings.HasPrefix(f, dir) {
+			continue
+		}
+
+		candidate := f
+		if i := strings.Index(f[len(dir):], "/"); i != -1 {
+			candidate = f[:len(dir)+i+1]
+		}
+		if len(candidates) == 0 || candidates[len(candidates)-1] != candidate {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+// completeRevisions returns the branches, tags and remotes starting with cur
+func completeRevisions(cur string) []string {
+	refs, _ := gitw.Refs(context.Background(), ".")
+	remotes, _ := gitw.Remotes(context.Background(), ".")
+
+	revisions := slices.Concat([]string{"HEAD"}, refs, remotes)
+	slices.Sort(revisions)
+	return withPrefix(slices.Compact(revisions), cur)
+}
+
+// withPrefix returns the candidates starting with prefix
+func withPrefix(candidates []string, prefix string) []string {
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
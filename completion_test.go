@@ -0,0 +1,170 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestCompletionScript(t *testing.T) {
+	cases := map[string]struct {
+		shell    string
+		contains string
+		wantErr  bool
+	}{
+		"bash": {
+			shell:    "bash",
+			contains: "_git_open ()",
+		},
+		"zsh": {
+			shell:    "zsh",
+			contains: "_git-open () {",
+		},
+		"fish": {
+			shell:    "fish",
+			contains: "__fish_git_using_command open",
+		},
+		"unsupported shell": {
+			shell:   "powershell",
+			wantErr: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			script, err := completionScript(c.shell)
+			if err != nil && !c.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			} else if err == nil && c.wantErr {
+				t.Fatalf("expected error:\n\t(GOT): nil")
+			} else if !strings.Contains(script, c.contains) {
+				t.Fatalf("unexpected script:\n\t(GOT): %q\n\t(WNT): contains %q", script, c.contains)
+			}
+		})
+	}
+}
+
+func TestComplete(t *testing.T) {
+	dir := t.TempDir()
+
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	for _, name := range []string{"main.go", "main_test.go", "open/open.go", "open/provider.go", "README.md"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "untracked.go"), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	run("init", "-b", "main")
+	run("config", "user.email", "test@example.com")
+	run("config", "user.name", "Test")
+	run("remote", "add", "origin", "https://github.com/example/repo.git")
+	run("add", "main.go", "main_test.go", "open", "README.md")
+	run("commit", "-m", "init")
+	run("tag", "v1.0.0")
+	run("branch", "feature")
+
+	t.Chdir(dir)
+
+	cases := map[string]struct {
+		words              []string
+		expectedCandidates []string
+	}{
+		"first argument": {
+			words:              []string{""},
+			expectedCandidates: []string{"completion", "help", "README.md", "main.go", "main_test.go", "open/"},
+		},
+		"first argument with a prefix": {
+			words:              []string{"ma"},
+			expectedCandidates: []string{"main.go", "main_test.go"},
+		},
+		"directory": {
+			words:              []string{"open/"},
+			expectedCandidates: []string{"open/open.go", "open/provider.go"},
+		},
+		"directory with a prefix": {
+			words:              []string{"open/p"},
+			expectedCandidates: []string{"open/provider.go"},
+		},
+		"second argument has no commands": {
+			words:              []string{"main.go", "h"},
+			expectedCandidates: nil,
+		},
+		"flags": {
+			words:              []string{"--s"},
+			expectedCandidates: []string{"--staged"},
+		},
+		"revisions": {
+			words:              []string{"--files", ""},
+			expectedCandidates: []string{"HEAD", "feature", "main", "origin", "v1.0.0"},
+		},
+		"revisions for ref": {
+			words:              []string{"--ref", "f"},
+			expectedCandidates: []string{"feature"},
+		},
+		"remotes": {
+			words:              []string{"--remote", ""},
+			expectedCandidates: []string{"origin"},
+		},
+		"views": {
+			words:              []string{"--view", "b"},
+			expectedCandidates: []string{"blame"},
+		},
+		"commands after an option value": {
+			words:              []string{"--remote", "origin", "he"},
+			expectedCandidates: []string{"help"},
+		},
+		"revisions with a prefix": {
+			words:              []string{"--files", "v"},
+			expectedCandidates: []string{"v1.0.0"},
+		},
+		"no targets after a mode": {
+			words:              []string{"--changed", ""},
+			expectedCandidates: nil,
+		},
+		"paths after explain": {
+			words:              []string{"-v", "open/o"},
+			expectedCandidates: []string{"open/open.go"},
+		},
+		"shells": {
+			words:              []string{"completion", ""},
+			expectedCandidates: []string{"bash", "fish", "zsh"},
+		},
+		"paths after double dash": {
+			words:              []string{"--", "h"},
+			expectedCandidates: nil,
+		},
+		"paths after double dash with a prefix": {
+			words:              []string{"--", "R"},
+			expectedCandidates: []string{"README.md"},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			candidates := complete(c.words)
+			if !cmp.Equal(candidates, c.expectedCandidates, cmpopts.EquateEmpty()) {
+				t.Fatalf("unexpected candidates:\n\t(GOT): %#v\n\t(WNT): %#v", candidates, c.expectedCandidates)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+# bash completion for git-open
+#
+# Requires git's own bash completion, which calls _git_open to complete `git open`.
+#
+#   source <(git open completion bash)
+
+_git_open ()
+{
+	local idx=${__git_cmd_idx:-1}
+	local IFS=$'\n'
+
+	COMPREPLY=($(__git open __complete "${words[@]:idx+1:cword-idx}"))
+	if [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == */ ]]; then
+		compopt -o nospace 2>/dev/null
+	fi
+}
@@ -0,0 +1,17 @@
+# fish completion for git-open
+#
+#   git open completion fish > ~/.config/fish/conf.d/git-open.fish
+
+function __git_open_complete
+    set -l tokens (commandline -opc)
+    set -l args
+    if test "$tokens[1]" = git-open
+        set args $tokens[2..-1]
+    else if set -l idx (contains -i -- open $tokens)
+        set args $tokens[(math $idx + 1)..-1]
+    end
+    git open __complete $args (commandline -ct) 2>/dev/null
+end
+
+complete -c git -n '__fish_git_using_command open' -f -a '(__git_open_complete)'
+complete -c git-open -f -a '(__git_open_complete)'
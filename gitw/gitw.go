@@ -1,11 +1,18 @@
 package gitw
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/ldez/go-git-cmd-wrapper/v2/config"
 	"github.com/ldez/go-git-cmd-wrapper/v2/git"
+	"github.com/ldez/go-git-cmd-wrapper/v2/lsfiles"
 	"github.com/ldez/go-git-cmd-wrapper/v2/revparse"
+	"github.com/ldez/go-git-cmd-wrapper/v2/status"
 	"github.com/ldez/go-git-cmd-wrapper/v2/types"
 )
 
@@ -26,8 +33,8 @@ func cwd(path string) types.Option {
 // with the Git directory specified by path
 //
 // git -C path rev-parse --absolute-git-dir
-func AbsoluteGitDir(path string) (string, error) {
-	out, err := git.RevParse(cwd(path), revparse.AbsoluteGitDir)
+func AbsoluteGitDir(ctx context.Context, path string) (string, error) {
+	out, err := git.RevParseWithContext(ctx, cwd(path), revparse.AbsoluteGitDir)
 	return strings.TrimSpace(out), err
 }
 
@@ -35,18 +42,21 @@ func AbsoluteGitDir(path string) (string, error) {
 // with the Git directory specified by path
 //
 // git -C path rev-parse --show-toplevel
-func Toplevel(path string) (string, error) {
-	out, err := git.RevParse(cwd(path), revparse.ShowToplevel)
+func Toplevel(ctx context.Context, path string) (string, error) {
+	out, err := git.RevParseWithContext(ctx, cwd(path), revparse.ShowToplevel)
 	return strings.TrimSpace(out), err
 }
 
-// RemoteURL returns the URL of the remote,
+// RemoteURL returns the URL of the named remote, or of the default remote when name is empty,
 // with the Git directory specified by path
 //
-// git -C path ls-remote --get-url
-func RemoteURL(path string) (string, error) {
-	out, err := git.Raw("ls-remote", cwd(path), func(g *types.Cmd) {
+// git -C path ls-remote --get-url name
+func RemoteURL(ctx context.Context, path, name string) (string, error) {
+	out, err := git.RawWithContext(ctx, "ls-remote", cwd(path), func(g *types.Cmd) {
 		g.AddOptions("--get-url")
+		if name != "" {
+			g.AddOptions(name)
+		}
 	})
 	return strings.TrimSpace(out), err
 }
@@ -57,24 +67,258 @@ func RemoteURL(path string) (string, error) {
 //
 // git -C path rev-parse --abbrev-ref HEAD
 // git -C path rev-parse HEAD
-func CurrentRef(path string) (string, error) {
-	out, err := git.RevParse(cwd(path), revparse.AbbrevRef(""), revparse.Args("HEAD"))
+func CurrentRef(ctx context.Context, path string) (string, error) {
+	out, err := git.RevParseWithContext(ctx, cwd(path), revparse.AbbrevRef(""), revparse.Args("HEAD"))
 	if err != nil {
 		return "", err
 	}
 
 	ref := strings.TrimSpace(out)
 	if ref == "HEAD" {
-		out, err = git.RevParse(cwd(path), revparse.Args("HEAD"))
+		out, err = git.RevParseWithContext(ctx, cwd(path), revparse.Args("HEAD"))
 		return strings.TrimSpace(out), err
 	}
 
 	return ref, nil
 }
 
-// ConfigGetRegexp returns trimmed git config output for all keys matching pattern.
+// SymbolicFullName returns the full name of rev, like `refs/heads/main` or `refs/tags/v1.0.0`,
+// with the Git directory specified by path.
+// Returns an empty string when rev is a commit SHA rather than a ref.
+//
+// git -C path rev-parse --symbolic-full-name rev
+func SymbolicFullName(ctx context.Context, path, rev string) (string, error) {
+	out, err := git.RevParseWithContext(ctx, cwd(path), revparse.SymbolicFullName, revparse.Args(rev))
+	return strings.TrimSpace(out), err
+}
+
+// StatusPaths returns the paths, relative to the root of the working tree, that are modified
+// in the working tree or index, excluding untracked files and deletions,
+// with the Git directory specified by path
+//
+// git -C path status --porcelain -z --untracked-files=no
+func StatusPaths(ctx context.Context, path string) ([]string, error) {
+	out, err := git.StatusWithContext(ctx, cwd(path), status.Porcelain(""), status.Null, status.UntrackedFiles("no"))
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		entry := fields[i]
+		if len(entry) < 4 {
+			continue
+		}
+
+		xy, p := entry[:2], entry[3:]
+		if strings.ContainsAny(xy, "RC") {
+			// Renames and copies are followed by the original path
+			i++
+		}
+		if strings.Contains(xy, "D") {
+			continue
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// StagedPaths returns the paths, relative to the root of the working tree, that are staged
+// in the index excluding deletions, with the Git directory specified by path
+//
+// git -C path diff --cached --name-only -z --diff-filter=d
+func StagedPaths(ctx context.Context, path string) ([]string, error) {
+	out, err := git.RawWithContext(ctx, "diff", cwd(path), func(g *types.Cmd) {
+		g.AddOptions("--cached")
+		g.AddOptions("--name-only")
+		g.AddOptions("-z")
+		g.AddOptions("--diff-filter=d")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return splitNull(out), nil
+}
+
+// CommitPaths returns the paths, relative to the root of the working tree, that are changed
+// by the commit rev excluding deletions, with the Git directory specified by path
+//
+// git -C path diff-tree --no-commit-id --name-only -r -z --root --diff-filter=d rev
+func CommitPaths(ctx context.Context, path, rev string) ([]string, error) {
+	out, err := git.RawWithContext(ctx, "diff-tree", cwd(path), func(g *types.Cmd) {
+		g.AddOptions("--no-commit-id")
+		g.AddOptions("--name-only")
+		g.AddOptions("-r")
+		g.AddOptions("-z")
+		g.AddOptions("--root")
+		g.AddOptions("--diff-filter=d")
+		g.AddOptions(rev)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return splitNull(out), nil
+}
+
+// Diff returns the zero-context patch of file, with the Git directory specified by path.
+// args select what is compared, like `HEAD` or `--cached`.
+//
+// git -C path diff -U0 args... -- file
+func Diff(ctx context.Context, path, file string, args ...string) (string, error) {
+	return git.RawWithContext(ctx, "diff", cwd(path), func(g *types.Cmd) {
+		g.AddOptions("-U0")
+		for _, arg := range args {
+			g.AddOptions(arg)
+		}
+		g.AddOptions("--")
+		g.AddOptions(file)
+	})
+}
+
+// DiffTree returns the zero-context patch of file introduced by the commit rev,
+// with the Git directory specified by path
+//
+// git -C path diff-tree --no-commit-id -p -U0 --root rev -- file
+func DiffTree(ctx context.Context, path, rev, file string) (string, error) {
+	return git.RawWithContext(ctx, "diff-tree", cwd(path), func(g *types.Cmd) {
+		g.AddOptions("--no-commit-id")
+		g.AddOptions("-p")
+		g.AddOptions("-U0")
+		g.AddOptions("--root")
+		g.AddOptions(rev)
+		g.AddOptions("--")
+		g.AddOptions(file)
+	})
+}
+
+// CommitSHA returns the full SHA of the commit named by rev,
+// with the Git directory specified by path
+//
+// git -C path rev-parse --verify rev^{commit}
+func CommitSHA(ctx context.Context, path, rev string) (string, error) {
+	out, err := git.RevParseWithContext(ctx, cwd(path), revparse.Verify, revparse.Args(rev+"^{commit}"))
+	return strings.TrimSpace(out), err
+}
+
+// ObjectType returns the type of the object named by object, like `blob` for a file or `tree` for a directory
+// named `ref:path`, with the Git directory specified by path
+//
+// git -C path cat-file -t object
+func ObjectType(ctx context.Context, path, object string) (string, error) {
+	out, err := git.RawWithContext(ctx, "cat-file", cwd(path), func(g *types.Cmd) {
+		g.AddOptions("-t")
+		g.AddOptions(object)
+	})
+	return strings.TrimSpace(out), err
+}
+
+// blameHeaderRegex matches the header of each line group in blame porcelain output, capturing the final line number
+var blameHeaderRegex = regexp.MustCompile(`(?m)^[0-9a-f]{40,64} [0-9]+ ([0-9]+)`)
+
+// FuncRange returns the first and last line of the function matching the funcname regex in file,
+// found with the xfuncname patterns of the file's diff driver, with the Git directory specified by path
+//
+// git -C path blame --porcelain -L :funcname -- file
+func FuncRange(ctx context.Context, path, file, funcname string) (start int, end int, err error) {
+	out, err := git.RawWithContext(ctx, "blame", cwd(path), func(g *types.Cmd) {
+		g.AddOptions("--porcelain")
+		g.AddOptions("-L")
+		g.AddOptions(":" + funcname)
+		g.AddOptions("--")
+		g.AddOptions(file)
+	})
+	if err != nil {
+		return 0, 0, errors.New(strings.TrimSpace(out))
+	}
+
+	for _, m := range blameHeaderRegex.FindAllStringSubmatch(out, -1) {
+		line, _ := strconv.Atoi(m[1])
+		if start == 0 || line < start {
+			start = line
+		}
+		end = max(end, line)
+	}
+	if start == 0 {
+		return 0, 0, fmt.Errorf("no match for %q in %s", funcname, file)
+	}
+	return start, end, nil
+}
+
+// LsFiles returns the tracked files matching pathspecs, relative to path
+//
+// git -C path ls-files -z -- pathspecs...
+func LsFiles(ctx context.Context, path string, pathspecs ...string) ([]string, error) {
+	out, err := git.LsFilesWithContext(ctx, cwd(path), lsfiles.Z, lsfiles.HyphenHyphen, lsfiles.Files(pathspecs...))
+	if err != nil {
+		return nil, err
+	}
+	return splitNull(out), nil
+}
+
+// IsIgnored reports whether file, relative to path, is ignored by a .gitignore or exclude file,
+// with the Git directory specified by path
+//
+// git -C path check-ignore -q -- file
+func IsIgnored(ctx context.Context, path, file string) bool {
+	_, err := git.RawWithContext(ctx, "check-ignore", cwd(path), func(g *types.Cmd) {
+		g.AddOptions("-q")
+		g.AddOptions("--")
+		g.AddOptions(file)
+	})
+	return err == nil
+}
+
+// Refs returns the short names of all branches, remote-tracking branches and tags,
+// with the Git directory specified by path
+//
+// git -C path for-each-ref --format=%(refname:short) refs/heads refs/remotes refs/tags
+func Refs(ctx context.Context, path string) ([]string, error) {
+	out, err := git.RawWithContext(ctx, "for-each-ref", cwd(path), func(g *types.Cmd) {
+		g.AddOptions("--format=%(refname:short)")
+		g.AddOptions("refs/heads")
+		g.AddOptions("refs/remotes")
+		g.AddOptions("refs/tags")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+// Remotes returns the names of all remotes, with the Git directory specified by path
+//
+// git -C path remote
+func Remotes(ctx context.Context, path string) ([]string, error) {
+	out, err := git.RemoteWithContext(ctx, cwd(path))
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+// splitNull splits NUL-terminated output into its non-empty fields
+func splitNull(out string) []string {
+	var fields []string
+	for f := range strings.SplitSeq(out, "\x00") {
+		if f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// ConfigGetRegexp returns trimmed git config output for all keys matching pattern,
+// with the Git directory specified by path.
 // Reads all config scopes (system, global, local) with local taking precedence over global for the same key.
-func ConfigGetRegexp(pattern string) string {
-	out, _ := git.Config(config.GetRegexp(pattern, ""))
+func ConfigGetRegexp(ctx context.Context, path, pattern string) string {
+	out, _ := git.ConfigWithContext(ctx, cwd(path), config.GetRegexp(pattern, ""))
+	return strings.TrimSpace(out)
+}
+
+// ConfigGetRegexpOrigin is like ConfigGetRegexp, but prefixes each line with the origin
+// of the key, like `file:/home/user/.gitconfig`, followed by a tab.
+func ConfigGetRegexpOrigin(ctx context.Context, path, pattern string) string {
+	out, _ := git.ConfigWithContext(ctx, cwd(path), config.ShowOrigin, config.GetRegexp(pattern, ""))
 	return strings.TrimSpace(out)
 }
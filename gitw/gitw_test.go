@@ -1,7 +1,12 @@
 package gitw
 
 import (
+	"context"
+	"errors"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -9,26 +14,11 @@ import (
 )
 
 func TestConfigGetRegexp(t *testing.T) {
-	dir := t.TempDir()
-
-	run := func(args ...string) {
-		t.Helper()
-		cmd := exec.Command("git", args...)
-		cmd.Dir = dir
-		if out, err := cmd.CombinedOutput(); err != nil {
-			t.Fatalf("git %v: %v\n%s", args, err, out)
-		}
-	}
+	dir := initRepo(t, "")
+	runGit(t, dir, "config", "open.https://git.example.dev.commitprefix", "commit")
+	runGit(t, dir, "config", "open.https://git.example.dev.pathprefix", "tree")
 
-	run("init")
-	run("config", "user.email", "test@example.com")
-	run("config", "user.name", "Test")
-	run("config", "open.https://git.example.dev.commitprefix", "commit")
-	run("config", "open.https://git.example.dev.pathprefix", "tree")
-
-	t.Chdir(dir)
-
-	out := ConfigGetRegexp(`^open\..*prefix$`)
+	out := ConfigGetRegexp(t.Context(), dir, `^open\..*prefix$`)
 	if !strings.Contains(out, "open.https://git.example.dev.commitprefix commit") {
 		t.Fatalf("unexpected output:\n\t(GOT): %q", out)
 	}
@@ -37,6 +27,17 @@ func TestConfigGetRegexp(t *testing.T) {
 	}
 }
 
+func TestConfigGetRegexpOrigin(t *testing.T) {
+	dir := initRepo(t, "")
+	runGit(t, dir, "config", "open.https://git.example.dev.commitprefix", "commit")
+
+	out := ConfigGetRegexpOrigin(t.Context(), dir, `^open\..*prefix$`)
+	expected := "file:.git/config\topen.https://git.example.dev.commitprefix commit"
+	if !strings.Contains(out, expected) {
+		t.Fatalf("unexpected output:\n\t(GOT): %q\n\t(WNT): contains %q", out, expected)
+	}
+}
+
 func TestCwd(t *testing.T) {
 	cases := map[string]struct {
 		path         string
@@ -70,26 +71,11 @@ func TestCwd(t *testing.T) {
 }
 
 func TestCurrentRef(t *testing.T) {
-	dir := t.TempDir()
-
-	run := func(args ...string) string {
-		t.Helper()
-		cmd := exec.Command("git", args...)
-		cmd.Dir = dir
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			t.Fatalf("git %v: %v\n%s", args, err, out)
-		}
-		return strings.TrimSpace(string(out))
-	}
-
-	run("init", "-b", "test-branch")
-	run("config", "user.email", "test@example.com")
-	run("config", "user.name", "Test")
-	run("commit", "--allow-empty", "-m", "init")
+	dir := initRepo(t, "")
+	runGit(t, dir, "switch", "-c", "test-branch")
 
 	t.Run("branch", func(t *testing.T) {
-		ref, err := CurrentRef(dir)
+		ref, err := CurrentRef(t.Context(), dir)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -99,10 +85,10 @@ func TestCurrentRef(t *testing.T) {
 	})
 
 	t.Run("detached HEAD", func(t *testing.T) {
-		sha := run("rev-parse", "HEAD")
-		run("checkout", "--detach")
+		sha := runGit(t, dir, "rev-parse", "HEAD")
+		runGit(t, dir, "checkout", "--detach")
 
-		ref, err := CurrentRef(dir)
+		ref, err := CurrentRef(t.Context(), dir)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -111,3 +97,293 @@ func TestCurrentRef(t *testing.T) {
 		}
 	})
 }
+
+func TestSymbolicFullName(t *testing.T) {
+	dir := initRepo(t, "")
+	runGit(t, dir, "tag", "v1.0.0")
+	sha := runGit(t, dir, "rev-parse", "HEAD")
+
+	cases := map[string]struct {
+		rev          string
+		expectedName string
+		wantErr      bool
+	}{
+		"branch": {
+			rev:          "main",
+			expectedName: "refs/heads/main",
+		},
+		"tag": {
+			rev:          "v1.0.0",
+			expectedName: "refs/tags/v1.0.0",
+		},
+		"commit": {
+			rev:          sha,
+			expectedName: "",
+		},
+		"unknown": {
+			rev:     "does-not-exist",
+			wantErr: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := SymbolicFullName(t.Context(), dir, c.rev)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != c.expectedName {
+				t.Fatalf("unexpected name:\n\t(GOT): %#v\n\t(WNT): %#v", got, c.expectedName)
+			}
+		})
+	}
+}
+
+func TestObjectType(t *testing.T) {
+	dir := initRepo(t, "")
+
+	if err := os.MkdirAll(filepath.Join(dir, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docs", "guide.md"), []byte("# Guide\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "docs")
+
+	cases := map[string]struct {
+		object       string
+		expectedType string
+		wantErr      bool
+	}{
+		"file": {
+			object:       "main:docs/guide.md",
+			expectedType: "blob",
+		},
+		"directory": {
+			object:       "main:docs",
+			expectedType: "tree",
+		},
+		"commit": {
+			object:       "main",
+			expectedType: "commit",
+		},
+		"unknown": {
+			object:  "main:does-not-exist",
+			wantErr: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ObjectType(t.Context(), dir, c.object)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != c.expectedType {
+				t.Fatalf("unexpected type:\n\t(GOT): %#v\n\t(WNT): %#v", got, c.expectedType)
+			}
+		})
+	}
+}
+
+func TestChangedPaths(t *testing.T) {
+	dir := initRepo(t, "")
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("a.txt", "a\n")
+	write("b.txt", "b\n")
+	write("c.txt", "c\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "files")
+
+	write("a.txt", "a\na\n")
+	runGit(t, dir, "add", "a.txt")
+	runGit(t, dir, "mv", "b.txt", "renamed b.txt")
+	runGit(t, dir, "rm", "c.txt")
+	write("untracked.txt", "u\n")
+
+	t.Run("status", func(t *testing.T) {
+		paths, err := StatusPaths(t.Context(), dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{"a.txt", "renamed b.txt"}
+		if !slices.Equal(paths, expected) {
+			t.Fatalf("unexpected paths:\n\t(GOT): %#v\n\t(WNT): %#v", paths, expected)
+		}
+	})
+
+	t.Run("staged", func(t *testing.T) {
+		paths, err := StagedPaths(t.Context(), dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{"a.txt", "renamed b.txt"}
+		if !slices.Equal(paths, expected) {
+			t.Fatalf("unexpected paths:\n\t(GOT): %#v\n\t(WNT): %#v", paths, expected)
+		}
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		runGit(t, dir, "commit", "-m", "change")
+
+		paths, err := CommitPaths(t.Context(), dir, "HEAD")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{"a.txt", "renamed b.txt"}
+		if !slices.Equal(paths, expected) {
+			t.Fatalf("unexpected paths:\n\t(GOT): %#v\n\t(WNT): %#v", paths, expected)
+		}
+
+		paths, err = CommitPaths(t.Context(), dir, "HEAD~1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected = []string{"a.txt", "b.txt", "c.txt"}
+		if !slices.Equal(paths, expected) {
+			t.Fatalf("unexpected paths:\n\t(GOT): %#v\n\t(WNT): %#v", paths, expected)
+		}
+	})
+}
+
+func TestFuncRange(t *testing.T) {
+	dir := initRepo(t, "")
+
+	src := "package main\n\n// first does nothing\nfunc first() {\n}\n\nfunc second() {\n\treturn\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "main.go")
+
+	cases := map[string]struct {
+		funcname      string
+		expectedStart int
+		expectedEnd   int
+		wantErr       bool
+	}{
+		"function followed by another": {
+			funcname:      "first",
+			expectedStart: 4,
+			expectedEnd:   6,
+		},
+		"last function in file": {
+			funcname:      "second",
+			expectedStart: 7,
+			expectedEnd:   9,
+		},
+		"no match": {
+			funcname: "third",
+			wantErr:  true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			start, end, err := FuncRange(t.Context(), dir, "main.go", c.funcname)
+			if err != nil && !c.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			} else if err == nil && c.wantErr {
+				t.Fatalf("expected error:\n\t(GOT): nil")
+			} else if start != c.expectedStart || end != c.expectedEnd {
+				t.Fatalf("unexpected range:\n\t(GOT): %d-%d\n\t(WNT): %d-%d", start, end, c.expectedStart, c.expectedEnd)
+			}
+		})
+	}
+}
+
+func TestIsIgnored(t *testing.T) {
+	dir := t.TempDir()
+
+	cmd := exec.Command("git", "init")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*.log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		file     string
+		expected bool
+	}{
+		"ignored file": {
+			file:     "debug.log",
+			expected: true,
+		},
+		"not ignored file": {
+			file:     "main.go",
+			expected: false,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			ignored := IsIgnored(t.Context(), dir, c.file)
+			if ignored != c.expected {
+				t.Fatalf("unexpected result:\n\t(GOT): %#v\n\t(WNT): %#v", ignored, c.expected)
+			}
+		})
+	}
+}
+
+func TestRemoteURL(t *testing.T) {
+	dir := initRepo(t, "https://github.com/example/fork.git")
+	runGit(t, dir, "remote", "add", "upstream", "https://github.com/example/repo.git")
+
+	cases := map[string]struct {
+		name        string
+		expectedURL string
+	}{
+		"default remote": {
+			name:        "",
+			expectedURL: "https://github.com/example/fork.git",
+		},
+		"named remote": {
+			name:        "upstream",
+			expectedURL: "https://github.com/example/repo.git",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			url, err := RemoteURL(t.Context(), dir, c.name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if url != c.expectedURL {
+				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", url, c.expectedURL)
+			}
+		})
+	}
+}
+
+func TestCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := Toplevel(ctx, "."); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error:\n\t(GOT): %#v\n\t(WNT): %#v", err, context.Canceled)
+	}
+}
@@ -1,41 +1,403 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"runtime/debug"
+	"slices"
+	"strings"
 
 	"github.com/arbourd/git-open/open"
 )
 
+// usage is the help text shown by `git open help`, `git open -h` and, as a man page, `git help open`
+const usage = `usage: git open [<options>] [--] [<target>...]
+   or: git open help
+   or: git open completion (bash|zsh|fish)
+   or: git open --version
+
+Opens the Git repository in the web browser. Each target opens separately and is
+either a commit SHA or a path, optionally suffixed with a line (main.go:42), a range
+of lines (main.go:42-50), a function (main.go::main) or a Markdown heading
+(README.md#usage). Without a target, the root of the repository is opened.
+
+Options:
+    --changed        open every tracked file changed in the working tree or index
+    --staged         open every file staged in the index
+    --files <rev>    open every file changed by the commit <rev>
+    --remote <name>  open the repository of the remote <name>, instead of the remote
+                     of the current branch or origin
+    --ref <ref>      open paths at the branch, tag or commit <ref>, instead of the
+                     current branch
+    --view <view>    open paths in the blame, raw or history view
+    -v, --explain    print each step taken to resolve the URL to stderr
+    --lenient        open the root of the repository when a path cannot be resolved,
+                     instead of failing
+    --version        print the version and exit
+    -h, --help       print this help and exit
+
+    --               treat the remaining arguments as paths, even when they look
+                     like commit SHAs or commands
+
+Commands:
+    help             print this help and exit, or with --man, print it as a man page
+    completion       print the completion script for bash, zsh or fish
+
+Exit status:
+    0    success
+    1    any other error
+    2    invalid options or arguments
+    3    not a Git repository
+    4    no remote is configured
+    5    the remote is a local path
+    6    no provider matches the host of the remote
+    7    a path is outside the repository
+    8    the provider for the remote is invalid in git config
+    9    the web browser cannot be opened
+    10   a path does not exist
+`
+
+// version is set at build time by `-ldflags "-X main.version=..."`, otherwise it is read from the build info
+var version string
+
+// command is the parsed command line
+type command struct {
+	targets []string
+
+	// paths is set when targets follow `--` and are always paths
+	paths bool
+
+	// changes is set when a set of changed files should be opened instead of targets
+	changes bool
+	mode    open.Changes
+	rev     string
+
+	// remote, ref and view select what is opened, see open.Options
+	remote string
+	ref    string
+	view   string
+
+	help    bool
+	man     bool
+	version bool
+	explain bool
+	lenient bool
+
+	// completion is the shell to print the completion script for
+	completion string
+	// complete is set when completion candidates for targets should be printed
+	complete bool
+}
+
+// Exit codes, so that wrappers can tell failures apart
+const (
+	exitError           = 1
+	exitUsage           = 2
+	exitNotRepository   = 3
+	exitNoRemote        = 4
+	exitLocalRemote     = 5
+	exitUnknownProvider = 6
+	exitPathOutsideRepo = 7
+	exitInvalidConfig   = 8
+	exitBrowser         = 9
+	exitPathNotFound    = 10
+)
+
 func main() {
-	arg, err := processArgs(os.Args)
+	cmd, err := parseArgs(os.Args[1:])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: \"%s\"\n", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "See 'git open help'.\n")
+		os.Exit(exitUsage)
 	}
 
-	url, err := open.GetURL(arg)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: \"%s\"\n", err)
-		os.Exit(1)
+	switch {
+	case cmd.man:
+		fmt.Print(manPage())
+		return
+	case cmd.help:
+		fmt.Print(usage)
+		return
+	case cmd.version:
+		fmt.Printf("git-open version %s\n", getVersion())
+		return
+	case cmd.completion != "":
+		script, err := completionScript(cmd.completion)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: \"%s\"\n", err)
+			os.Exit(exitUsage)
+		}
+		fmt.Print(script)
+		return
+	case cmd.complete:
+		for _, c := range complete(cmd.targets) {
+			fmt.Println(c)
+		}
+		return
 	}
 
-	fmt.Printf("Opening %s in your browser.\n", url)
-	err = open.InBrowser(url)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: unable to open in browser: \"%s\"\n", err)
-		os.Exit(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	urls, errs := getURLs(ctx, cmd)
+	for _, err := range errs {
+		report(err)
+	}
+	if cmd.changes && len(urls) == 0 && len(errs) == 0 {
+		fmt.Println("No changed files to open.")
+	}
+
+	errs = append(errs, openURLs(urls, open.InBrowser)...)
+	if len(errs) > 0 {
+		os.Exit(exitCode(errs[0]))
 	}
 }
 
-// processArgs returns a single argument or returns an error if more than 1 argument is provided
-func processArgs(args []string) (string, error) {
-	switch len(args) {
-	case 1:
-		return "", nil
-	case 2:
-		return args[1], nil
+// openURLs opens every URL with browse, reporting the URLs that cannot be opened, and returns their errors
+func openURLs(urls []string, browse func(url string) error) []error {
+	var errs []error
+	for _, url := range urls {
+		fmt.Printf("Opening %s in your browser.\n", url)
+		if err := browse(url); err != nil {
+			report(err)
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// report prints err and its hint
+func report(err error) {
+	fmt.Fprintf(os.Stderr, "error: \"%s\"\n", err)
+	if hint := open.Hint(err); hint != "" {
+		fmt.Fprintf(os.Stderr, "hint: %s\n", hint)
+	}
+}
+
+// exitCode returns the exit code for err
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, open.ErrNotRepository):
+		return exitNotRepository
+	case errors.Is(err, open.ErrNoRemote):
+		return exitNoRemote
+	case errors.Is(err, open.ErrLocalRemote):
+		return exitLocalRemote
+	case errors.Is(err, open.ErrUnknownProvider):
+		return exitUnknownProvider
+	case errors.Is(err, open.ErrPathOutsideRepo):
+		return exitPathOutsideRepo
+	case errors.Is(err, open.ErrInvalidConfig):
+		return exitInvalidConfig
+	case errors.Is(err, open.ErrBrowser):
+		return exitBrowser
+	case errors.Is(err, open.ErrPathNotFound):
+		return exitPathNotFound
 	default:
-		return "", fmt.Errorf("received %d args, accepts 1", len(args)-1)
+		return exitError
+	}
+}
+
+// parseArgs parses the command line arguments, without the program name, into a command
+func parseArgs(args []string) (command, error) {
+	var cmd command
+	var changed, staged bool
+	var files string
+
+	fs := flag.NewFlagSet("git open", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&changed, "changed", false, "")
+	fs.BoolVar(&staged, "staged", false, "")
+	fs.StringVar(&files, "files", "", "")
+	fs.StringVar(&cmd.remote, "remote", "", "")
+	fs.StringVar(&cmd.ref, "ref", "", "")
+	fs.StringVar(&cmd.view, "view", "", "")
+	fs.BoolVar(&cmd.version, "version", false, "")
+	fs.BoolVar(&cmd.explain, "explain", false, "")
+	fs.BoolVar(&cmd.explain, "v", false, "")
+	fs.BoolVar(&cmd.lenient, "lenient", false, "")
+	fs.BoolVar(&cmd.help, "h", false, "")
+	fs.BoolVar(&cmd.help, "help", false, "")
+
+	// Options may follow targets, like with git, so parsing resumes after each target. Commands take the
+	// remaining arguments as they are.
+	cmd.targets = []string{}
+	for rest := args; ; {
+		if err := fs.Parse(rest); err != nil {
+			return command{}, err
+		}
+		consumed := rest[:len(rest)-len(fs.Args())]
+		rest = fs.Args()
+		if endsWithSeparator(fs, consumed) {
+			cmd.paths = true
+			cmd.targets = append(cmd.targets, rest...)
+			break
+		}
+		if len(rest) == 0 {
+			break
+		}
+		if len(cmd.targets) == 0 && slices.Contains([]string{"help", "completion", "__complete"}, rest[0]) {
+			cmd.targets = rest
+			break
+		}
+		cmd.targets, rest = append(cmd.targets, rest[0]), rest[1:]
+	}
+	if cmd.help {
+		return command{help: true}, nil
+	}
+
+	if !cmd.paths && len(cmd.targets) > 0 {
+		switch name, rest := cmd.targets[0], cmd.targets[1:]; name {
+		case "help":
+			if len(rest) > 1 || (len(rest) == 1 && rest[0] != "--man") {
+				return command{}, fmt.Errorf("help accepts no args, received %d", len(rest))
+			}
+			return command{help: true, man: len(rest) == 1}, nil
+		case "completion":
+			if len(rest) != 1 {
+				return command{}, fmt.Errorf("completion accepts 1 arg, received %d", len(rest))
+			}
+			return command{completion: rest[0]}, nil
+		case "__complete":
+			return command{complete: true, targets: rest}, nil
+		}
+	}
+
+	var modes []string
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "changed", "staged", "files":
+			modes = append(modes, "--"+f.Name)
+		}
+	})
+	if len(modes) > 1 {
+		return command{}, fmt.Errorf("%s cannot be used together", strings.Join(modes, " and "))
+	}
+	if len(modes) == 1 && len(cmd.targets) > 0 {
+		return command{}, fmt.Errorf("%s accepts no targets, received %d", modes[0], len(cmd.targets))
+	}
+
+	if cmd.view != "" && !slices.Contains(open.Views, open.View(cmd.view)) {
+		return command{}, fmt.Errorf("unknown view: %q, expected one of: blame, raw, history", cmd.view)
+	}
+
+	switch {
+	case changed:
+		cmd.changes, cmd.mode = true, open.Changed
+	case staged:
+		cmd.changes, cmd.mode = true, open.Staged
+	case files != "":
+		cmd.changes, cmd.mode, cmd.rev = true, open.Files, files
+	case len(modes) == 1 && modes[0] == "--files":
+		return command{}, errors.New("--files requires a revision")
+	}
+
+	return cmd, nil
+}
+
+// endsWithSeparator reports whether the arguments consumed by parsing fs end with the `--` separator, rather than
+// with `--` as the value of an option like `--ref --`
+func endsWithSeparator(fs *flag.FlagSet, consumed []string) bool {
+	for i := 0; i < len(consumed); i++ {
+		arg := consumed[i]
+		if arg == "--" {
+			return i == len(consumed)-1
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		f := fs.Lookup(name)
+		if f == nil || hasValue {
+			continue
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			// The option takes the next argument as its value
+			i++
+		}
+	}
+	return false
+}
+
+// getURLs returns the URLs to open for a command, and the errors of the targets that cannot be resolved.
+// Every target is resolved, even when another fails.
+func getURLs(ctx context.Context, cmd command) ([]string, []error) {
+	opts := open.Options{
+		Remote:  cmd.remote,
+		Ref:     cmd.ref,
+		View:    open.View(cmd.view),
+		Lenient: cmd.lenient,
+	}
+	if cmd.explain {
+		opts.Explain = os.Stderr
+	}
+
+	if cmd.changes {
+		results, err := open.ResolveChanges(ctx, opts, cmd.mode, cmd.rev)
+		if err != nil {
+			return nil, []error{err}
+		}
+
+		urls := make([]string, 0, len(results))
+		for _, res := range results {
+			urls = append(urls, res.URL)
+		}
+		return urls, nil
+	}
+
+	if cmd.paths {
+		opts.Type = open.Path
+	}
+
+	targets := cmd.targets
+	if len(targets) == 0 {
+		targets = []string{""}
+	}
+
+	urls := make([]string, 0, len(targets))
+	var errs []error
+	for _, target := range targets {
+		opts.Target = target
+		res, err := open.Resolve(ctx, opts)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		urls = append(urls, res.URL)
+	}
+	return urls, errs
+}
+
+// getVersion returns the version set at build time, or the main module version from the build info
+func getVersion() string {
+	if version != "" {
+		return strings.TrimPrefix(version, "v")
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return strings.TrimPrefix(info.Main.Version, "v")
+	}
+	return "(devel)"
+}
+
+// manPage returns the usage text as a roff man page, so that it can be installed as git-open(1) for `git help open`
+func manPage() string {
+	escaper := strings.NewReplacer(`\`, `\e`, "-", `\-`)
+
+	var b strings.Builder
+	b.WriteString(".TH GIT-OPEN 1\n")
+	b.WriteString(".SH NAME\n")
+	b.WriteString("git-open \\- open the Git repository in the web browser\n")
+	b.WriteString(".SH SYNOPSIS\n")
+	b.WriteString(".nf\n")
+	for line := range strings.SplitSeq(strings.TrimRight(usage, "\n"), "\n") {
+		line = escaper.Replace(line)
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			line = `\&` + line
+		}
+		b.WriteString(line + "\n")
 	}
+	b.WriteString(".fi\n")
+	return b.String()
 }
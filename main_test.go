@@ -1,41 +1,282 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/arbourd/git-open/open"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
-func TestProcessArgs(t *testing.T) {
+func TestParseArgs(t *testing.T) {
 	cases := map[string]struct {
-		args        []string
-		expectedArg string
-		wantErr     bool
+		args            []string
+		expectedCommand command
+		wantErr         bool
 	}{
 		"no argument": {
-			args:        []string{"git-open"},
-			expectedArg: "",
+			args:            []string{},
+			expectedCommand: command{targets: []string{}},
 		},
 		"one argument": {
-			args:        []string{"git-open", "LICENSE"},
-			expectedArg: "LICENSE",
+			args:            []string{"LICENSE"},
+			expectedCommand: command{targets: []string{"LICENSE"}},
 		},
 		"two arguments": {
-			args:        []string{"git-open", "LICENSE", "README.md"},
-			expectedArg: "",
-			wantErr:     true,
+			args:            []string{"LICENSE", "README.md"},
+			expectedCommand: command{targets: []string{"LICENSE", "README.md"}},
+		},
+		"double dash forces paths": {
+			args:            []string{"--", "7605d91"},
+			expectedCommand: command{targets: []string{"7605d91"}, paths: true},
+		},
+		"double dash without targets": {
+			args:            []string{"--"},
+			expectedCommand: command{targets: []string{}, paths: true},
+		},
+		"double dash after a flag": {
+			args:            []string{"--files", "HEAD", "--"},
+			expectedCommand: command{targets: []string{}, paths: true, changes: true, mode: open.Files, rev: "HEAD"},
+		},
+		"option after a target": {
+			args:            []string{"main.go", "--ref", "v1.0.0", "LICENSE"},
+			expectedCommand: command{targets: []string{"main.go", "LICENSE"}, ref: "v1.0.0"},
+		},
+		"option after a target with changes": {
+			args:    []string{"LICENSE", "--changed"},
+			wantErr: true,
+		},
+		"unknown option after a target": {
+			args:    []string{"LICENSE", "--nope"},
+			wantErr: true,
+		},
+		"double dash after targets": {
+			args:            []string{"main.go", "--", "--ref"},
+			expectedCommand: command{targets: []string{"main.go", "--ref"}, paths: true},
+		},
+		"double dash as the value of an option": {
+			args:            []string{"--ref", "--", "7605d91"},
+			expectedCommand: command{targets: []string{"7605d91"}, ref: "--"},
+		},
+		"double dash after the value of an option": {
+			args:            []string{"--ref=v1.0.0", "--explain", "--", "7605d91"},
+			expectedCommand: command{targets: []string{"7605d91"}, ref: "v1.0.0", explain: true, paths: true},
+		},
+		"changed": {
+			args:            []string{"--changed"},
+			expectedCommand: command{targets: []string{}, changes: true, mode: open.Changed},
+		},
+		"staged": {
+			args:            []string{"--staged"},
+			expectedCommand: command{targets: []string{}, changes: true, mode: open.Staged},
+		},
+		"staged with a target": {
+			args:    []string{"--staged", "LICENSE"},
+			wantErr: true,
+		},
+		"files": {
+			args:            []string{"--files", "HEAD~1"},
+			expectedCommand: command{targets: []string{}, changes: true, mode: open.Files, rev: "HEAD~1"},
+		},
+		"files with an equals sign": {
+			args:            []string{"--files=HEAD~1"},
+			expectedCommand: command{targets: []string{}, changes: true, mode: open.Files, rev: "HEAD~1"},
+		},
+		"files without a revision": {
+			args:    []string{"--files"},
+			wantErr: true,
+		},
+		"files with an empty revision": {
+			args:    []string{"--files", ""},
+			wantErr: true,
+		},
+		"changed and staged": {
+			args:    []string{"--changed", "--staged"},
+			wantErr: true,
+		},
+		"unknown flag": {
+			args:    []string{"--unknown"},
+			wantErr: true,
+		},
+		"help command": {
+			args:            []string{"help"},
+			expectedCommand: command{help: true},
+		},
+		"help command as a man page": {
+			args:            []string{"help", "--man"},
+			expectedCommand: command{help: true, man: true},
+		},
+		"help command with arguments": {
+			args:    []string{"help", "LICENSE"},
+			wantErr: true,
+		},
+		"help as a path": {
+			args:            []string{"--", "help"},
+			expectedCommand: command{targets: []string{"help"}, paths: true},
+		},
+		"help flag": {
+			args:            []string{"--help"},
+			expectedCommand: command{help: true},
+		},
+		"short help flag": {
+			args:            []string{"-h"},
+			expectedCommand: command{help: true},
+		},
+		"completion": {
+			args:            []string{"completion", "bash"},
+			expectedCommand: command{completion: "bash"},
+		},
+		"completion without a shell": {
+			args:    []string{"completion"},
+			wantErr: true,
+		},
+		"complete": {
+			args:            []string{"__complete", "--files", "ma"},
+			expectedCommand: command{complete: true, targets: []string{"--files", "ma"}},
+		},
+		"complete an empty word": {
+			args:            []string{"__complete", ""},
+			expectedCommand: command{complete: true, targets: []string{""}},
+		},
+		"explain": {
+			args:            []string{"--explain", "LICENSE"},
+			expectedCommand: command{targets: []string{"LICENSE"}, explain: true},
+		},
+		"short explain": {
+			args:            []string{"-v", "--changed"},
+			expectedCommand: command{explain: true, changes: true, mode: open.Changed},
+		},
+		"lenient": {
+			args:            []string{"--lenient", "LICENSE"},
+			expectedCommand: command{targets: []string{"LICENSE"}, lenient: true},
+		},
+		"remote, ref and view": {
+			args:            []string{"--remote", "upstream", "--ref", "v1.0.0", "--view", "blame", "main.go"},
+			expectedCommand: command{targets: []string{"main.go"}, remote: "upstream", ref: "v1.0.0", view: "blame"},
+		},
+		"view with changes": {
+			args:            []string{"--view=history", "--staged"},
+			expectedCommand: command{view: "history", changes: true, mode: open.Staged},
+		},
+		"unknown view": {
+			args:    []string{"--view", "source", "main.go"},
+			wantErr: true,
+		},
+		"version": {
+			args:            []string{"--version"},
+			expectedCommand: command{targets: []string{}, version: true},
 		},
 	}
 
 	for name, c := range cases {
 		t.Run(name, func(t *testing.T) {
-			arg, err := processArgs(c.args)
+			cmd, err := parseArgs(c.args)
 
 			if err != nil && !c.wantErr {
 				t.Fatalf("unexpected error:\n\t(GOT): %#v\n\t(WNT): nil", err)
 			} else if err == nil && c.wantErr {
 				t.Fatalf("expected error:\n\t(GOT): nil\n")
-			} else if arg != c.expectedArg {
-				t.Fatalf("unexpected arg:\n\t(GOT): %#v\n\t(WNT): %#v", arg, c.expectedArg)
+			} else if !c.wantErr && !cmp.Equal(cmd, c.expectedCommand, cmp.AllowUnexported(command{}), cmpopts.EquateEmpty()) {
+				t.Fatalf("unexpected command:\n\t(GOT): %#v\n\t(WNT): %#v", cmd, c.expectedCommand)
 			}
 		})
 	}
 }
+
+func TestExitCode(t *testing.T) {
+	cases := map[string]struct {
+		err          error
+		expectedCode int
+	}{
+		"other error": {
+			err:          errors.New("unknown revision"),
+			expectedCode: exitError,
+		},
+		"not a repository": {
+			err:          &open.Error{Err: open.ErrNotRepository},
+			expectedCode: exitNotRepository,
+		},
+		"no remote": {
+			err:          &open.Error{Err: open.ErrNoRemote},
+			expectedCode: exitNoRemote,
+		},
+		"local remote": {
+			err:          &open.Error{Err: open.ErrLocalRemote},
+			expectedCode: exitLocalRemote,
+		},
+		"wrapped unknown provider": {
+			err:          &open.Error{Err: fmt.Errorf("%w: %q", open.ErrUnknownProvider, "example.com")},
+			expectedCode: exitUnknownProvider,
+		},
+		"path outside repository": {
+			err:          fmt.Errorf("%w: /", open.ErrPathOutsideRepo),
+			expectedCode: exitPathOutsideRepo,
+		},
+		"path not found": {
+			err:          &open.Error{Err: fmt.Errorf("%w: mian.go", open.ErrPathNotFound), Hint: `did you mean "main.go"?`},
+			expectedCode: exitPathNotFound,
+		},
+		"invalid config": {
+			err:          &open.Error{Err: open.ErrInvalidConfig},
+			expectedCode: exitInvalidConfig,
+		},
+		"browser": {
+			err:          &open.Error{Err: fmt.Errorf("%w: %w", open.ErrBrowser, errors.New("not found"))},
+			expectedCode: exitBrowser,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			code := exitCode(c.err)
+			if code != c.expectedCode {
+				t.Fatalf("unexpected exit code:\n\t(GOT): %#v\n\t(WNT): %#v", code, c.expectedCode)
+			}
+		})
+	}
+}
+
+func TestGetURLs(t *testing.T) {
+	urls, errs := getURLs(t.Context(), command{targets: []string{"main.go", "mian.go", "LICENSE"}})
+	if len(urls) != 2 || !strings.HasSuffix(urls[0], "/main.go") || !strings.HasSuffix(urls[1], "/LICENSE") {
+		t.Fatalf("unexpected urls:\n\t(GOT): %#v\n\t(WNT): the urls of main.go and LICENSE", urls)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], open.ErrPathNotFound) {
+		t.Fatalf("unexpected errors:\n\t(GOT): %#v\n\t(WNT): %#v", errs, []error{open.ErrPathNotFound})
+	}
+}
+
+func TestOpenURLs(t *testing.T) {
+	urls := []string{"https://github.com/a", "https://github.com/b", "https://github.com/c"}
+	browserErr := &open.Error{Err: open.ErrBrowser}
+
+	var opened []string
+	errs := openURLs(urls, func(url string) error {
+		opened = append(opened, url)
+		if url == urls[1] {
+			return browserErr
+		}
+		return nil
+	})
+	if !slices.Equal(opened, urls) {
+		t.Fatalf("unexpected opened urls:\n\t(GOT): %#v\n\t(WNT): %#v", opened, urls)
+	}
+	if len(errs) != 1 || errs[0] != browserErr {
+		t.Fatalf("unexpected errors:\n\t(GOT): %#v\n\t(WNT): %#v", errs, []error{browserErr})
+	}
+}
+
+func TestManPage(t *testing.T) {
+	man := manPage()
+
+	if !strings.HasPrefix(man, ".TH GIT-OPEN 1\n") {
+		t.Fatalf("unexpected man page header:\n\t(GOT): %q", man)
+	}
+	if !strings.Contains(man, `usage: git open [<options>] [\-\-] [<target>...]`) {
+		t.Fatalf("man page is missing the usage:\n\t(GOT): %q", man)
+	}
+}
@@ -0,0 +1,157 @@
+package open
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/arbourd/git-open/gitw"
+)
+
+// Changes represents a set of changed files to open
+type Changes int
+
+const (
+	// Changed is every tracked file changed in the working tree or index. Untracked files are left out, as they
+	// won't exist on the remote.
+	Changed Changes = iota
+
+	// Staged is every file staged in the index
+	Staged
+
+	// Files is every file changed by a specific commit
+	Files
+)
+
+// GetChangedURLs returns the URL of every file in the set of changes, resolved with the default Options,
+// with a line anchor pointing to the first changed hunk of each file. rev names the commit when c is Files
+// and is otherwise ignored.
+func GetChangedURLs(c Changes, rev string) ([]string, error) {
+	results, err := ResolveChanges(context.Background(), Options{}, c, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0, len(results))
+	for _, res := range results {
+		urls = append(urls, res.URL)
+	}
+	return urls, nil
+}
+
+// ResolveChanges returns the URL, and the parts it was built from, of every file in the set of changes,
+// with a line anchor pointing to the first changed hunk of each file. rev names the commit when c is Files
+// and is otherwise ignored, as are the Target, Type and Lenient options.
+func ResolveChanges(ctx context.Context, opts Options, c Changes, rev string) ([]Result, error) {
+	if opts.View != "" && !slices.Contains(Views, opts.View) {
+		return nil, fmt.Errorf("unknown view: %q", opts.View)
+	}
+
+	r, err := newResolver(ctx, opts.RepoDir, opts.Explain)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := r.resolveChanges(opts, c, rev)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return results, redactError(err)
+}
+
+// resolveChanges returns the URL, and the parts it was built from, of every file in the set of changes
+func (r *resolver) resolveChanges(opts Options, c Changes, rev string) ([]Result, error) {
+	gitroot, err := r.gitRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := r.resolveRepository(gitroot, opts.Remote, opts.Ref)
+	if err != nil {
+		return nil, err
+	}
+	p, ref, kind := repo.provider, repo.ref, repo.refKind
+
+	var paths []string
+	switch c {
+	case Changed:
+		paths, err = gitw.StatusPaths(r.ctx, gitroot)
+	case Staged:
+		paths, err = gitw.StagedPaths(r.ctx, gitroot)
+	case Files:
+		// Link to the files as they were at the commit, so line anchors stay accurate
+		ref, err = gitw.CommitSHA(r.ctx, gitroot, rev)
+		if err != nil {
+			return nil, fmt.Errorf("unknown revision: %q", rev)
+		}
+		p, kind = withRefKind(p, CommitRef), CommitRef
+		paths, err = gitw.CommitPaths(r.ctx, gitroot, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.explainf("changes: %d files", len(paths))
+
+	results := make([]Result, 0, len(paths))
+	for _, path := range paths {
+		var diff string
+		switch c {
+		case Changed:
+			diff, _ = gitw.Diff(r.ctx, gitroot, path, "HEAD")
+		case Staged:
+			diff, _ = gitw.Diff(r.ctx, gitroot, path, "--cached")
+		case Files:
+			diff, _ = gitw.DiffTree(r.ctx, gitroot, ref, path)
+		}
+
+		line := firstHunkLine(diff)
+		r.explainf("changes: %q first changed at line %d", path, line)
+
+		res := Result{
+			Type:      Path,
+			View:      opts.View,
+			Provider:  p.BaseURL(),
+			Remote:    repo.remote,
+			Host:      repo.host,
+			Repo:      repo.repo,
+			Ref:       ref,
+			RefKind:   kind,
+			Path:      path,
+			LineStart: line,
+		}
+		if opts.View == "" {
+			res.URL = pathURL(p, true, res.Repo, res.Ref, res.Path, res.LineStart, 0)
+		} else {
+			var ok bool
+			if v, isViewer := p.(Viewer); isViewer {
+				res.URL, ok = v.ViewURL(opts.View, res.Repo, res.Ref, res.Path, res.LineStart, 0)
+			}
+			if !ok {
+				return nil, fmt.Errorf("the %s view is not supported by %s", opts.View, p.BaseURL())
+			}
+		}
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+// hunkHeaderRegex matches the header of a unified diff hunk, capturing the new start line
+var hunkHeaderRegex = regexp.MustCompile(`(?m)^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// firstHunkLine returns the first line of the new file touched by the first hunk of a zero-context diff,
+// or 0 when there are no hunks or the whole file was added
+func firstHunkLine(diff string) int {
+	m := hunkHeaderRegex.FindStringSubmatch(diff)
+	if m == nil || strings.Contains(diff, "\n--- /dev/null\n") {
+		return 0
+	}
+
+	// A pure deletion reports the line before the removed lines, which may be 0
+	start, _ := strconv.Atoi(m[1])
+	return max(start, 1)
+}
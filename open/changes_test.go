@@ -0,0 +1,145 @@
+package open
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestGetChangedURLs(t *testing.T) {
+	dir := initRepo(t, "https://github.com/example/repo.git")
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("a.txt", "1\n2\n3\n4\n5\n")
+	write("b.txt", "1\n2\n3\n")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "init")
+
+	write("a.txt", "1\n2\n3\nfour\n5\n")
+	write("b.txt", "1\ntwo\n3\n")
+	runGit(t, dir, "add", "b.txt")
+	write("new.txt", "new\n")
+
+	t.Chdir(dir)
+
+	t.Run("changed", func(t *testing.T) {
+		urls, err := GetChangedURLs(Changed, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{
+			"https://github.com/example/repo/blob/main/a.txt#L4",
+			"https://github.com/example/repo/blob/main/b.txt#L2",
+		}
+		if !slices.Equal(urls, expected) {
+			t.Fatalf("unexpected urls:\n\t(GOT): %#v\n\t(WNT): %#v", urls, expected)
+		}
+	})
+
+	t.Run("staged", func(t *testing.T) {
+		urls, err := GetChangedURLs(Staged, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{"https://github.com/example/repo/blob/main/b.txt#L2"}
+		if !slices.Equal(urls, expected) {
+			t.Fatalf("unexpected urls:\n\t(GOT): %#v\n\t(WNT): %#v", urls, expected)
+		}
+	})
+
+	t.Run("staged in the blame view", func(t *testing.T) {
+		results, err := ResolveChanges(t.Context(), Options{RepoDir: dir, View: Blame}, Staged, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []Result{{
+			URL:       "https://github.com/example/repo/blame/main/b.txt#L2",
+			Type:      Path,
+			View:      Blame,
+			Provider:  "https://github.com",
+			Remote:    "https://github.com/example/repo.git",
+			Host:      "github.com",
+			Repo:      "example/repo",
+			Ref:       "main",
+			RefKind:   Branch,
+			Path:      "b.txt",
+			LineStart: 2,
+		}}
+		if !slices.Equal(results, expected) {
+			t.Fatalf("unexpected results:\n\t(GOT): %#v\n\t(WNT): %#v", results, expected)
+		}
+	})
+
+	t.Run("files", func(t *testing.T) {
+		runGit(t, dir, "commit", "-m", "change")
+		sha := runGit(t, dir, "rev-parse", "HEAD")
+
+		urls, err := GetChangedURLs(Files, "HEAD")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{"https://github.com/example/repo/blob/" + sha + "/b.txt#L2"}
+		if !slices.Equal(urls, expected) {
+			t.Fatalf("unexpected urls:\n\t(GOT): %#v\n\t(WNT): %#v", urls, expected)
+		}
+	})
+
+	t.Run("files with unknown revision", func(t *testing.T) {
+		_, err := GetChangedURLs(Files, "does-not-exist")
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
+
+func TestFirstHunkLine(t *testing.T) {
+	cases := map[string]struct {
+		diff         string
+		expectedLine int
+	}{
+		"no diff": {
+			diff:         "",
+			expectedLine: 0,
+		},
+		"modified line": {
+			diff:         "--- a/a.txt\n+++ b/a.txt\n@@ -4 +4 @@\n-4\n+four\n",
+			expectedLine: 4,
+		},
+		"first of several hunks": {
+			diff:         "--- a/a.txt\n+++ b/a.txt\n@@ -2,0 +3,2 @@\n+x\n+y\n@@ -10 +12 @@ func\n-a\n+b\n",
+			expectedLine: 3,
+		},
+		"insertion at the start of a file": {
+			diff:         "--- a/a.txt\n+++ b/a.txt\n@@ -0,0 +1 @@\n+x\n",
+			expectedLine: 1,
+		},
+		"deletion reports the preceding line": {
+			diff:         "--- a/a.txt\n+++ b/a.txt\n@@ -5,2 +4,0 @@\n-5\n-6\n",
+			expectedLine: 4,
+		},
+		"deletion at the start of a file": {
+			diff:         "--- a/a.txt\n+++ b/a.txt\n@@ -1 +0,0 @@\n-1\n",
+			expectedLine: 1,
+		},
+		"new file has no anchor": {
+			diff:         "new file mode 100644\n--- /dev/null\n+++ b/a.txt\n@@ -0,0 +1,3 @@\n+1\n+2\n+3\n",
+			expectedLine: 0,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			line := firstHunkLine(c.diff)
+			if line != c.expectedLine {
+				t.Fatalf("unexpected line:\n\t(GOT): %#v\n\t(WNT): %#v", line, c.expectedLine)
+			}
+		})
+	}
+}
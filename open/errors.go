@@ -0,0 +1,58 @@
+package open
+
+import (
+	"errors"
+)
+
+var (
+	// ErrNotRepository is returned when the current directory is not within a Git repository
+	ErrNotRepository = errors.New("not a git repository")
+
+	// ErrNoRemote is returned when the repository has no remote to open
+	ErrNoRemote = errors.New("no remote configured")
+
+	// ErrLocalRemote is returned when the remote is a local path instead of a URL, below no local path of
+	// a provider, and not a local clone with a remote URL
+	ErrLocalRemote = errors.New("local remotes are not supported")
+
+	// ErrUnknownProvider is returned when no provider matches the host of the remote
+	ErrUnknownProvider = errors.New("unable to find provider for")
+
+	// ErrPathNotFound is returned when a path does not exist
+	ErrPathNotFound = errors.New("no such file or directory")
+
+	// ErrPathOutsideRepo is returned when a path is not within the repository
+	ErrPathOutsideRepo = errors.New("path is outside the repository")
+
+	// ErrInvalidConfig is returned when the provider for the remote is defined in git config, but is invalid
+	ErrInvalidConfig = errors.New("invalid provider in git config")
+
+	// ErrBrowser is returned when the web browser cannot be opened
+	ErrBrowser = errors.New("unable to open in browser")
+)
+
+// Error is an error with an actionable hint for resolving it.
+// It wraps one of the sentinel errors, like ErrUnknownProvider, for use with errors.Is.
+type Error struct {
+	Err  error
+	Hint string
+}
+
+// Error returns the message of the wrapped error
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Hint returns the actionable hint of err, or an empty string if err has no hint
+func Hint(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Hint
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package open
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestHint(t *testing.T) {
+	cases := map[string]struct {
+		err          error
+		expectedHint string
+	}{
+		"nil": {
+			err:          nil,
+			expectedHint: "",
+		},
+		"error without hint": {
+			err:          errors.New("plain"),
+			expectedHint: "",
+		},
+		"error with hint": {
+			err:          &Error{Err: ErrNoRemote, Hint: "add a remote"},
+			expectedHint: "add a remote",
+		},
+		"wrapped error with hint": {
+			err:          fmt.Errorf("wrapped: %w", &Error{Err: ErrNoRemote, Hint: "add a remote"}),
+			expectedHint: "add a remote",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			hint := Hint(c.err)
+			if hint != c.expectedHint {
+				t.Fatalf("unexpected hint:\n\t(GOT): %#v\n\t(WNT): %#v", hint, c.expectedHint)
+			}
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	err := &Error{Err: fmt.Errorf("%w: %q", ErrUnknownProvider, "example.com"), Hint: "add a provider"}
+
+	if err.Error() != `unable to find provider for: "example.com"` {
+		t.Fatalf("unexpected message:\n\t(GOT): %q", err.Error())
+	}
+	if !errors.Is(err, ErrUnknownProvider) {
+		t.Fatalf("expected errors.Is ErrUnknownProvider")
+	}
+
+	var e *Error
+	if !errors.As(fmt.Errorf("wrapped: %w", err), &e) || e != err {
+		t.Fatalf("expected errors.As *Error")
+	}
+}
@@ -0,0 +1,35 @@
+package open
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// runGit runs git with args in dir, failing the test on error, and returns its trimmed output
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// initRepo returns a repository on the main branch with an empty commit, and the origin remote unless remote is
+// empty
+func initRepo(t *testing.T, remote string) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	runGit(t, dir, "init", "-b", "main")
+	runGit(t, dir, "config", "user.email", "test@example.com")
+	runGit(t, dir, "config", "user.name", "Test")
+	if remote != "" {
+		runGit(t, dir, "remote", "add", "origin", remote)
+	}
+	runGit(t, dir, "commit", "--allow-empty", "-m", "init")
+	return dir
+}
@@ -0,0 +1,128 @@
+package open
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/arbourd/git-open/gitw"
+)
+
+// maxLocalRemoteDepth limits following the remotes of local clones, which may point at each other
+const maxLocalRemoteDepth = 8
+
+// localPath returns the absolute path of a local remote, a path or `file://` URL, with relative paths
+// in gitroot
+func localPath(gitroot, remote string) string {
+	if rest, ok := cutPrefixFold(remote, "file://"); ok {
+		// Drop the host of `file://host/path`, which is always local
+		remote = rest
+		if u, err := url.Parse("file://" + rest); err == nil {
+			remote = u.Path
+		}
+		// Drop the `/` before the drive letter of `file:///C:/repos/repo.git` on Windows
+		if strings.HasPrefix(remote, "/") && filepath.VolumeName(filepath.FromSlash(remote[1:])) != "" {
+			remote = remote[1:]
+		}
+		remote = filepath.FromSlash(remote)
+	}
+
+	remote = expandHome(remote)
+	if !filepath.IsAbs(remote) {
+		remote = filepath.Join(gitroot, remote)
+	}
+	return filepath.Clean(remote)
+}
+
+// cutPrefixFold is like strings.CutPrefix, ignoring case
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+// localPathsOf returns the local paths of p, mirrors whose repositories open on it
+func localPathsOf(p Provider) []string {
+	pp, ok := p.(PrefixProvider)
+	if !ok || pp.hostPattern != nil {
+		return nil
+	}
+	return pp.localPaths
+}
+
+// mapLocalPath returns the web remote of the repository at dir, below the longest local path of the candidates,
+// or at a local path with a repository, or false when it is at or below none. The rest of dir below the local
+// path is appended to its repository.
+func mapLocalPath(dir string, candidates []Provider) (remote string, localPath string, ok bool) {
+	var baseURL, repo string
+	for _, p := range candidates {
+		for _, lp := range localPathsOf(p) {
+			path, r := splitLocalPath(lp)
+			if len(path) <= len(localPath) {
+				continue
+			}
+			if (r != "" && dir == path) || strings.HasPrefix(dir, strings.TrimSuffix(path, string(filepath.Separator))+string(filepath.Separator)) {
+				baseURL, localPath, repo = p.BaseURL(), path, r
+			}
+		}
+	}
+	if baseURL == "" {
+		return "", "", false
+	}
+
+	rel := strings.Trim(filepath.ToSlash(strings.TrimPrefix(dir, localPath)), "/")
+	rel = strings.TrimSuffix(strings.Trim(repo+"/"+rel, "/"), ".bundle")
+	return baseURL + "/" + rel, localPath, true
+}
+
+// isLocalClone reports whether dir is the root of a Git repository, bare or with a working tree, rather than
+// a bundle or a directory within another repository
+func (r *resolver) isLocalClone(dir string) bool {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return false
+	}
+	gitdir, err := gitw.AbsoluteGitDir(r.ctx, dir)
+	if err != nil {
+		return false
+	}
+	gitdir, _ = filepath.EvalSymlinks(gitdir)
+	dir, _ = filepath.EvalSymlinks(dir)
+	return gitdir == dir || gitdir == filepath.Join(dir, ".git")
+}
+
+// resolveLocalRemote returns the web remote of a local remote of the repository at gitroot: mapped from the
+// longest local path of a provider it is below, or else the remote of the local clone, followed through up to
+// maxLocalRemoteDepth local clones
+func (r *resolver) resolveLocalRemote(gitroot, remote string, candidates []Provider) (string, error) {
+	for range maxLocalRemoteDepth {
+		dir := localPath(gitroot, remote)
+		if mapped, lp, ok := mapLocalPath(dir, candidates); ok {
+			r.explainf("remote: %s, mapped from %s below the local path %s", mapped, dir, lp)
+			return mapped, nil
+		}
+		if !r.isLocalClone(dir) {
+			r.explainf("remote: %s is below no local path and is not a local clone", dir)
+			break
+		}
+
+		next, err := gitw.RemoteURL(r.ctx, dir, "")
+		if err != nil || next == "" {
+			r.explainf("remote: the local clone %s has no remote", dir)
+			break
+		}
+		next = r.normalizeRemote(dir, next)
+		r.explainf("remote: %s, the remote of the local clone %s", redactURL(next), dir)
+		if host, _, err := parseRepository(next); err == nil && host != "" {
+			return next, nil
+		}
+		gitroot, remote = dir, next
+	}
+
+	return "", &Error{
+		Err:  ErrLocalRemote,
+		Hint: "map the local path to a provider with `git config --global --add open.<url>.localpath <path>[=<repo>]`, or point the remote at the hosted repository with `git remote set-url <remote> <url>`",
+	}
+}
@@ -0,0 +1,200 @@
+package open
+
+import (
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fileURL returns the `file://` URL of the absolute path p on host, like `file:///C:/repos/repo.git` on Windows
+func fileURL(host, p string) string {
+	p = filepath.ToSlash(p)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Host: host, Path: p}).String()
+}
+
+func TestLocalPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	gitroot := filepath.Join(home, "src", "repo")
+
+	// The drive letter of a `file://` URL is only a volume on Windows
+	drivePath := filepath.FromSlash("/C:/repos/repo.git")
+	if runtime.GOOS == "windows" {
+		drivePath = `C:\repos\repo.git`
+	}
+
+	cases := map[string]struct {
+		remote       string
+		expectedPath string
+	}{
+		"absolute": {
+			remote:       filepath.Join(home, "mirrors", "repo.git"),
+			expectedPath: filepath.Join(home, "mirrors", "repo.git"),
+		},
+		"relative": {
+			remote:       filepath.Join("..", "upstream"),
+			expectedPath: filepath.Join(home, "src", "upstream"),
+		},
+		"home": {
+			remote:       "~/mirrors/repo.git",
+			expectedPath: filepath.Join(home, "mirrors", "repo.git"),
+		},
+		"file url": {
+			remote:       fileURL("", filepath.Join(home, "mirrors", "my repo.git")),
+			expectedPath: filepath.Join(home, "mirrors", "my repo.git"),
+		},
+		"file url with a host": {
+			remote:       fileURL("localhost", filepath.Join(home, "mirrors", "repo.git")),
+			expectedPath: filepath.Join(home, "mirrors", "repo.git"),
+		},
+		"file url with a drive letter": {
+			remote:       "file:///C:/repos/repo.git",
+			expectedPath: drivePath,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if path := localPath(gitroot, c.remote); path != c.expectedPath {
+				t.Fatalf("unexpected path:\n\t(GOT): %#v\n\t(WNT): %#v", path, c.expectedPath)
+			}
+		})
+	}
+}
+
+func TestMapLocalPath(t *testing.T) {
+	mirrors := t.TempDir()
+
+	var candidates []Provider
+	for _, c := range []ProviderConfig{
+		{BaseURL: "https://github.com", Type: "github", LocalPaths: []string{mirrors, filepath.Join(mirrors, "tools", "cli.git") + "=arbourd/git-open"}},
+		{BaseURL: "https://gitlab.com", Type: "gitlab", LocalPaths: []string{filepath.Join(mirrors, "gitlab"), filepath.Join(mirrors, "team") + "=group/team"}},
+	} {
+		p, err := NewProvider(c)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		candidates = append(candidates, p)
+	}
+
+	cases := map[string]struct {
+		dir            string
+		expectedRemote string
+		expectedOK     bool
+	}{
+		"below a local path": {
+			dir:            filepath.Join(mirrors, "org", "repo.git"),
+			expectedRemote: "https://github.com/org/repo.git",
+			expectedOK:     true,
+		},
+		"longest local path": {
+			dir:            filepath.Join(mirrors, "gitlab", "group", "sub", "repo"),
+			expectedRemote: "https://gitlab.com/group/sub/repo",
+			expectedOK:     true,
+		},
+		"bundle": {
+			dir:            filepath.Join(mirrors, "org", "repo.bundle"),
+			expectedRemote: "https://github.com/org/repo",
+			expectedOK:     true,
+		},
+		"local path itself": {
+			dir:        mirrors,
+			expectedOK: false,
+		},
+		"local path with a repository": {
+			dir:            filepath.Join(mirrors, "tools", "cli.git"),
+			expectedRemote: "https://github.com/arbourd/git-open",
+			expectedOK:     true,
+		},
+		"below a local path with a repository": {
+			dir:            filepath.Join(mirrors, "team", "repo"),
+			expectedRemote: "https://gitlab.com/group/team/repo",
+			expectedOK:     true,
+		},
+		"sibling with the same prefix": {
+			dir:        mirrors + "-old" + string(filepath.Separator) + filepath.Join("org", "repo"),
+			expectedOK: false,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			remote, _, ok := mapLocalPath(c.dir, candidates)
+			if remote != c.expectedRemote || ok != c.expectedOK {
+				t.Fatalf("unexpected remote:\n\t(GOT): %#v, %#v\n\t(WNT): %#v, %#v", remote, ok, c.expectedRemote, c.expectedOK)
+			}
+		})
+	}
+}
+
+func TestResolveLocalRemote(t *testing.T) {
+	resetRegistry(t)
+
+	mirrors := t.TempDir()
+	p, err := NewProvider(ProviderConfig{BaseURL: "https://gitlab.com", Type: "gitlab", LocalPaths: []string{mirrors}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Register(p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// upstream is a local clone of GitHub, and clone a local clone of upstream
+	upstream := initRepo(t, "https://github.com/arbourd/git-open.git")
+	clone := initRepo(t, upstream)
+	bundle := filepath.Join(t.TempDir(), "repo.bundle")
+	cmd := exec.Command("git", "bundle", "create", bundle, "HEAD")
+	cmd.Dir = upstream
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git bundle: %v\n%s", err, out)
+	}
+
+	cases := map[string]struct {
+		remote      string
+		expectedURL string
+		wantErr     bool
+	}{
+		"mapped local path": {
+			remote:      filepath.Join(mirrors, "team", "repo.git"),
+			expectedURL: "https://gitlab.com/team/repo/-/commit/7605d91",
+		},
+		"mapped file url": {
+			remote:      fileURL("", filepath.Join(mirrors, "team", "repo.git")),
+			expectedURL: "https://gitlab.com/team/repo/-/commit/7605d91",
+		},
+		"local clone": {
+			remote:      upstream,
+			expectedURL: "https://github.com/arbourd/git-open/commit/7605d91",
+		},
+		"local clone of a local clone": {
+			remote:      clone,
+			expectedURL: "https://github.com/arbourd/git-open/commit/7605d91",
+		},
+		"unmapped bundle": {
+			remote:  bundle,
+			wantErr: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := initRepo(t, c.remote)
+			res, err := Resolve(t.Context(), Options{RepoDir: dir, Target: "7605d91"})
+			if err != nil && !c.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			} else if err == nil && c.wantErr {
+				t.Fatalf("expected error:\n\t(GOT): nil")
+			}
+			if res.URL != c.expectedURL {
+				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", res.URL, c.expectedURL)
+			}
+		})
+	}
+}
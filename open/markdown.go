@@ -0,0 +1,181 @@
+package open
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Heading styles are the rules providers use to derive anchors from Markdown headings
+const (
+	// githubHeadings lowercases, drops punctuation and replaces spaces with `-`
+	githubHeadings = "github"
+
+	// gitlabHeadings is like githubHeadings, but collapses repeated `-`
+	gitlabHeadings = "gitlab"
+
+	// bitbucketHeadings is like gitlabHeadings, prefixed with `markdown-header-`
+	bitbucketHeadings = "bitbucket"
+)
+
+// renderedExts are the file extensions that providers render instead of showing source
+var renderedExts = []string{".md", ".markdown", ".mdown", ".mkdn", ".mkd", ".rst", ".adoc", ".asciidoc", ".org", ".textile", ".rdoc", ".ipynb"}
+
+// markdownExts are the file extensions of Markdown files
+var markdownExts = []string{".md", ".markdown", ".mdown", ".mkdn", ".mkd"}
+
+// isRendered reports whether providers render the file at path instead of showing its source
+func isRendered(p string) bool {
+	return slices.Contains(renderedExts, strings.ToLower(path.Ext(p)))
+}
+
+// stripHeading splits a trailing heading suffix like `#providers` from arg, returning the path and heading
+func stripHeading(arg string) (path string, heading string) {
+	i := strings.LastIndex(arg, "#")
+	if i <= 0 || i == len(arg)-1 {
+		return arg, ""
+	}
+	return arg[:i], arg[i+1:]
+}
+
+var (
+	atxHeadingRegex    = regexp.MustCompile(`^ {0,3}#{1,6}(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextHeadingRegex = regexp.MustCompile(`^ {0,3}(?:=+|-+)[ \t]*$`)
+	codeFenceRegex     = regexp.MustCompile("^ {0,3}(```|~~~)")
+	inlineLinkRegex    = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+)
+
+// markdownHeadings returns the text of every ATX and setext heading in a Markdown document,
+// ignoring fenced code blocks
+func markdownHeadings(content string) []string {
+	var headings []string
+	var fence, prev string
+
+	s := bufio.NewScanner(strings.NewReader(content))
+	for s.Scan() {
+		line := s.Text()
+
+		if m := codeFenceRegex.FindStringSubmatch(line); m != nil {
+			if fence == "" {
+				fence = m[1]
+			} else if fence == m[1] {
+				fence = ""
+			}
+			prev = ""
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+
+		switch {
+		case atxHeadingRegex.MatchString(line):
+			headings = append(headings, atxHeadingRegex.FindStringSubmatch(line)[1])
+			prev = ""
+			continue
+		case setextHeadingRegex.MatchString(line) && strings.TrimSpace(prev) != "":
+			headings = append(headings, strings.TrimSpace(prev))
+			prev = ""
+			continue
+		}
+		prev = line
+	}
+
+	return headings
+}
+
+// headingText returns the plain text of a heading, without inline links, code or emphasis markup
+func headingText(heading string) string {
+	heading = inlineLinkRegex.ReplaceAllString(heading, "$1")
+	return strings.NewReplacer("`", "", "*", "").Replace(heading)
+}
+
+// slugify returns the anchor of a heading's text according to a provider's heading style
+func slugify(style, text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(headingText(text)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsNumber(r), unicode.Is(unicode.Mn, r), r == '_', r == '-':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	slug := b.String()
+
+	switch style {
+	case gitlabHeadings, bitbucketHeadings:
+		for strings.Contains(slug, "--") {
+			slug = strings.ReplaceAll(slug, "--", "-")
+		}
+	}
+	if style == bitbucketHeadings {
+		slug = "markdown-header-" + strings.Trim(slug, "-")
+	}
+	return slug
+}
+
+// headingAnchors returns the unique anchor of every heading according to a provider's heading style,
+// numbering duplicates in document order
+func headingAnchors(style string, headings []string) []string {
+	sep := "-"
+	if style == bitbucketHeadings {
+		sep = "_"
+	}
+
+	seen := make(map[string]int)
+	anchors := make([]string, 0, len(headings))
+	for _, h := range headings {
+		slug := slugify(style, h)
+		anchor := slug
+		if n := seen[slug]; n > 0 {
+			anchor = slug + sep + strconv.Itoa(n)
+		}
+		seen[slug]++
+		anchors = append(anchors, anchor)
+	}
+	return anchors
+}
+
+// headingAnchor returns the anchor of heading in the Markdown file at rel, relative to the gitroot,
+// according to a provider's heading style
+func headingAnchor(style, gitroot, rel, heading string) (string, error) {
+	if !slices.Contains(markdownExts, strings.ToLower(path.Ext(rel))) {
+		return "", fmt.Errorf("headings are only supported in Markdown files: %s", rel)
+	}
+
+	content, err := os.ReadFile(filepath.Join(gitroot, filepath.FromSlash(rel)))
+	if err != nil {
+		return "", err
+	}
+
+	anchor, err := findHeading(style, string(content), heading)
+	if err != nil {
+		return "", fmt.Errorf("%w in %s", err, rel)
+	}
+	return anchor, nil
+}
+
+// findHeading returns the anchor of the heading in a Markdown document that matches heading,
+// given as either its text or its anchor, or an error if the document has no such heading
+func findHeading(style, content, heading string) (string, error) {
+	want := heading
+	if style == bitbucketHeadings {
+		want = strings.TrimPrefix(want, "markdown-header-")
+	}
+	want = slugify(style, want)
+
+	for _, anchor := range headingAnchors(style, markdownHeadings(content)) {
+		if anchor == want || anchor == heading {
+			return anchor, nil
+		}
+	}
+	return "", fmt.Errorf("heading not found: %q", heading)
+}
@@ -0,0 +1,176 @@
+package open
+
+import (
+	"slices"
+	"testing"
+)
+
+const markdown = `# git-open
+
+## Usage
+
+Some text.
+
+` + "```" + `console
+# not a heading
+` + "```" + `
+
+### Open a file, or a ` + "`folder`" + `!
+
+Providers & [hosts](https://example.com)
+-----------------------------------------
+
+## Usage
+
+#hashtag is not a heading
+`
+
+func TestMarkdownHeadings(t *testing.T) {
+	expected := []string{
+		"git-open",
+		"Usage",
+		"Open a file, or a `folder`!",
+		"Providers & [hosts](https://example.com)",
+		"Usage",
+	}
+
+	headings := markdownHeadings(markdown)
+	if !slices.Equal(headings, expected) {
+		t.Fatalf("unexpected headings:\n\t(GOT): %#v\n\t(WNT): %#v", headings, expected)
+	}
+}
+
+func TestHeadingAnchors(t *testing.T) {
+	headings := markdownHeadings(markdown)
+
+	cases := map[string]struct {
+		style           string
+		expectedAnchors []string
+	}{
+		"github": {
+			style:           githubHeadings,
+			expectedAnchors: []string{"git-open", "usage", "open-a-file-or-a-folder", "providers--hosts", "usage-1"},
+		},
+		"default is github": {
+			style:           "",
+			expectedAnchors: []string{"git-open", "usage", "open-a-file-or-a-folder", "providers--hosts", "usage-1"},
+		},
+		"gitlab": {
+			style:           gitlabHeadings,
+			expectedAnchors: []string{"git-open", "usage", "open-a-file-or-a-folder", "providers-hosts", "usage-1"},
+		},
+		"bitbucket": {
+			style: bitbucketHeadings,
+			expectedAnchors: []string{
+				"markdown-header-git-open",
+				"markdown-header-usage",
+				"markdown-header-open-a-file-or-a-folder",
+				"markdown-header-providers-hosts",
+				"markdown-header-usage_1",
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			anchors := headingAnchors(c.style, headings)
+			if !slices.Equal(anchors, c.expectedAnchors) {
+				t.Fatalf("unexpected anchors:\n\t(GOT): %#v\n\t(WNT): %#v", anchors, c.expectedAnchors)
+			}
+		})
+	}
+}
+
+func TestFindHeading(t *testing.T) {
+	cases := map[string]struct {
+		style          string
+		heading        string
+		expectedAnchor string
+		wantErr        bool
+	}{
+		"anchor": {
+			style:          githubHeadings,
+			heading:        "usage",
+			expectedAnchor: "usage",
+		},
+		"text": {
+			style:          githubHeadings,
+			heading:        "Usage",
+			expectedAnchor: "usage",
+		},
+		"duplicate anchor": {
+			style:          githubHeadings,
+			heading:        "usage-1",
+			expectedAnchor: "usage-1",
+		},
+		"bitbucket anchor": {
+			style:          bitbucketHeadings,
+			heading:        "markdown-header-usage",
+			expectedAnchor: "markdown-header-usage",
+		},
+		"bitbucket text": {
+			style:          bitbucketHeadings,
+			heading:        "usage",
+			expectedAnchor: "markdown-header-usage",
+		},
+		"heading inside a code block": {
+			style:   githubHeadings,
+			heading: "not-a-heading",
+			wantErr: true,
+		},
+		"missing heading": {
+			style:   githubHeadings,
+			heading: "installation",
+			wantErr: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			anchor, err := findHeading(c.style, markdown, c.heading)
+			if err != nil && !c.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			} else if err == nil && c.wantErr {
+				t.Fatalf("expected error:\n\t(GOT): nil")
+			} else if anchor != c.expectedAnchor {
+				t.Fatalf("unexpected anchor:\n\t(GOT): %#v\n\t(WNT): %#v", anchor, c.expectedAnchor)
+			}
+		})
+	}
+}
+
+func TestStripHeading(t *testing.T) {
+	cases := map[string]struct {
+		arg             string
+		expectedPath    string
+		expectedHeading string
+	}{
+		"no heading": {
+			arg:          "README.md",
+			expectedPath: "README.md",
+		},
+		"heading": {
+			arg:             "README.md#usage",
+			expectedPath:    "README.md",
+			expectedHeading: "usage",
+		},
+		"empty heading falls back to full arg": {
+			arg:          "README.md#",
+			expectedPath: "README.md#",
+		},
+		"no path before heading falls back to full arg": {
+			arg:          "#usage",
+			expectedPath: "#usage",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			path, heading := stripHeading(c.arg)
+			if path != c.expectedPath || heading != c.expectedHeading {
+				t.Fatalf("unexpected result:\n\t(GOT): path=%#v heading=%#v\n\t(WNT): path=%#v heading=%#v",
+					path, heading, c.expectedPath, c.expectedHeading)
+			}
+		})
+	}
+}
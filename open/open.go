@@ -1,7 +1,11 @@
 package open
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/url"
 	"os"
 	"os/exec"
@@ -9,6 +13,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -16,12 +21,17 @@ import (
 	"github.com/arbourd/git-open/gitw"
 )
 
-// Type represents the type of Git URL to open
+// Type represents the type of Git URL to open.
+//
+// Auto is the zero value, so Options detect the type unless it is set.
 type Type int
 
 const (
+	// Auto detects the type of the target: a commit SHA, a path or the root when empty
+	Auto Type = iota
+
 	// Commit is a specific commit in the repository
-	Commit Type = iota
+	Commit
 
 	// Path is a file, folder or path in the repository
 	Path
@@ -30,6 +40,92 @@ const (
 	Root
 )
 
+// View represents a provider's view of a path, other than the default file and directory view
+type View string
+
+const (
+	// Blame is who last changed each line of a file
+	Blame View = "blame"
+
+	// Raw is the raw content of a file
+	Raw View = "raw"
+
+	// History is the commits that changed a path
+	History View = "history"
+)
+
+// Views is every supported View
+var Views = []View{Blame, Raw, History}
+
+// RefKind represents whether a ref is a branch, tag or commit
+type RefKind string
+
+const (
+	// Branch is a branch, or a ref that cannot be classified
+	Branch RefKind = "branch"
+
+	// Tag is a tag
+	Tag RefKind = "tag"
+
+	// CommitRef is a commit SHA
+	CommitRef RefKind = "commit"
+)
+
+// Options configures how Resolve resolves a URL
+type Options struct {
+	// RepoDir is the directory the repository is found from and relative paths are resolved against.
+	// Defaults to the current directory.
+	RepoDir string
+
+	// Target is a commit SHA or a path, optionally suffixed with a line, a range of lines, a function or
+	// a Markdown heading. An empty target is the root of the repository.
+	Target string
+
+	// Type is the type of Target. Defaults to Auto.
+	Type Type
+
+	// Remote is the name of the remote to open. Defaults to the remote of the current branch, or origin.
+	Remote string
+
+	// Ref is the branch, tag or commit to open paths at. Defaults to the current branch, or commit when detached.
+	Ref string
+
+	// View is the provider's view to open paths in. Defaults to the file and directory view.
+	View View
+
+	// Lenient opens the root of the repository instead of failing when a path cannot be resolved
+	Lenient bool
+
+	// Explain, when set, receives a description of each step taken to resolve the URL
+	Explain io.Writer
+}
+
+// Result is a resolved URL and the parts it was built from
+type Result struct {
+	URL  string
+	Type Type
+	View View
+
+	// Provider is the base URL of the provider hosting the repository
+	Provider string
+	// Remote is the URL of the remote
+	Remote string
+	Host   string
+	Repo   string
+	Ref    string
+	// RefKind is whether Ref is a branch, tag or commit
+	RefKind RefKind
+
+	// Commit is the commit SHA, when Type is Commit
+	Commit string
+	// Path is relative to the root of the repository, with `/` separators, when Type is Path
+	Path      string
+	LineStart int
+	LineEnd   int
+	// Anchor is the Markdown heading anchor
+	Anchor string
+}
+
 // InBrowser opens a URL in the default browser
 func InBrowser(url string) error {
 	var cmd *exec.Cmd
@@ -43,102 +139,493 @@ func InBrowser(url string) error {
 		cmd = exec.Command("xdg-open", "--", url)
 	}
 
-	return cmd.Start()
+	if err := cmd.Start(); err != nil {
+		return &Error{
+			Err:  fmt.Errorf("%w: %w", ErrBrowser, err),
+			Hint: "copy the URL into your browser instead",
+		}
+	}
+	return nil
 }
 
-// GetURL returns the URL to open based on the arg provided
+// GetURL returns the URL to open based on the arg provided, resolved with the default Options
 func GetURL(arg string) (string, error) {
-	gitroot, err := gitw.Toplevel(".")
+	res, err := Resolve(context.Background(), Options{Target: arg})
+	return res.URL, err
+}
+
+// Resolve returns the URL to open, and the parts it was built from, for the target of opts.
+// Git is run from opts.RepoDir and stops when ctx is done.
+func Resolve(ctx context.Context, opts Options) (Result, error) {
+	if opts.View != "" && !slices.Contains(Views, opts.View) {
+		return Result{}, fmt.Errorf("unknown view: %q", opts.View)
+	}
+
+	r, err := newResolver(ctx, opts.RepoDir, opts.Explain)
 	if err != nil {
-		// If toplevel fails, we might be in a bare repo
-		gitroot, err = gitw.AbsoluteGitDir(".")
-		if err != nil {
-			return "", fmt.Errorf("not a git repository")
+		return Result{}, err
+	}
+
+	res, err := r.resolve(opts)
+	if err != nil && ctx.Err() != nil {
+		return Result{}, ctx.Err()
+	}
+	return res, redactError(err)
+}
+
+// resolver resolves URLs for a directory, running Git with a context
+type resolver struct {
+	ctx context.Context
+	// dir is the absolute directory relative paths are resolved against
+	dir     string
+	explain io.Writer
+}
+
+// newResolver returns a resolver for dir, or the current directory when dir is empty
+func newResolver(ctx context.Context, dir string, explain io.Writer) (*resolver, error) {
+	if dir == "" {
+		dir = "."
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	return &resolver{ctx: ctx, dir: dir, explain: explain}, nil
+}
+
+// explainf describes a step taken to resolve a URL to the explain writer, when set
+func (r *resolver) explainf(format string, a ...any) {
+	if r.explain != nil {
+		fmt.Fprintln(r.explain, "explain: "+redact(fmt.Sprintf(format, a...)))
+	}
+}
+
+// abs returns path joined to the resolver's directory, unless it is absolute
+func (r *resolver) abs(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(r.dir, path)
+}
+
+// targetType returns the Type of arg, detecting it when t is Auto
+func (r *resolver) targetType(arg string, t Type) Type {
+	switch {
+	case arg == "":
+		r.explainf("type: root, no target was provided")
+		return Root
+	case t == Commit:
+		r.explainf("type: commit, %q is always a commit", arg)
+		return Commit
+	case t == Path:
+		r.explainf("type: path, %q is always a path", arg)
+		return Path
+	case t == Root:
+		r.explainf("type: root, %q is ignored", arg)
+		return Root
+	}
+
+	t = parseType(arg)
+	switch t {
+	case Commit:
+		if _, err := os.Stat(r.abs(arg)); err == nil {
+			r.explainf("type: path, %q looks like a commit SHA but names a file", arg)
+			return Path
 		}
+		r.explainf("type: commit, %q looks like a commit SHA and does not name a file", arg)
+	case Path:
+		r.explainf("type: path, %q does not look like a commit SHA", arg)
+	}
+	return t
+}
+
+// resolve returns the URL to open, and the parts it was built from, for the target of opts
+func (r *resolver) resolve(opts Options) (Result, error) {
+	res := Result{Type: r.targetType(opts.Target, opts.Type), View: opts.View}
+
+	gitroot, err := r.gitRoot()
+	if err != nil {
+		return Result{}, err
 	}
 
-	t := parseType(arg)
-	if t == Commit {
-		if _, err := os.Stat(arg); err == nil {
-			t = Path
+	var heading string
+	switch res.Type {
+	case Commit:
+		if opts.View != "" {
+			return Result{}, fmt.Errorf("the %s view is only supported for paths", opts.View)
+		}
+		res.Commit = opts.Target
+	case Path:
+		arg := opts.Target
+		// Prefer the literal argument when it names a real file, with or without its line suffix, so a `#` in a
+		// file name like `f#1.txt:1` is not a heading
+		stripped, _, _, _ := stripLine(arg)
+		if _, err := os.Stat(r.abs(arg)); err != nil {
+			if _, err := os.Stat(r.abs(stripped)); err != nil {
+				arg, heading = stripHeading(arg)
+			}
+		}
+		res.Path, res.LineStart, res.LineEnd, err = r.parsePath(arg, gitroot)
+		switch {
+		case err != nil && opts.Lenient:
+			r.explainf("path: unable to resolve %q: %v, falling back to the repository root", arg, err)
+		case err != nil:
+			r.explainf("path: unable to resolve %q: %v", arg, err)
+			return Result{}, err
+		case res.Path == "":
+			r.explainf("path: %q is the repository root", arg)
+		case res.LineEnd > 0:
+			r.explainf("path: %q resolves to %q, lines %d-%d", arg, res.Path, res.LineStart, res.LineEnd)
+		case res.LineStart > 0:
+			r.explainf("path: %q resolves to %q, line %d", arg, res.Path, res.LineStart)
+		default:
+			r.explainf("path: %q resolves to %q", arg, res.Path)
+		}
+		if heading != "" {
+			r.explainf("path: heading %q", heading)
+		}
+		if res.Path != "" {
+			r.warnUntracked(gitroot, res.Path)
 		}
 	}
-	var lstart, lend int
-	if t == Path {
-		// Ignore parsePath errors: invalid or out-of-repo paths fall back to the root URL
-		arg, lstart, lend, _ = parsePath(arg, gitroot)
+
+	if opts.View == Blame || opts.View == Raw {
+		if info, err := os.Stat(filepath.Join(gitroot, filepath.FromSlash(res.Path))); res.Path == "" || (err == nil && info.IsDir()) {
+			return Result{}, fmt.Errorf("the %s view is only supported for files", opts.View)
+		}
 	}
 
-	remote, ref, err := getRemoteRef(gitroot)
+	repo, err := r.resolveRepository(gitroot, opts.Remote, opts.Ref)
 	if err != nil {
-		return "", err
+		return Result{}, err
+	}
+	p := repo.provider
+	res.Provider, res.Remote, res.Host, res.Repo, res.Ref = p.BaseURL(), repo.remote, repo.host, repo.repo, repo.ref
+	res.RefKind = repo.refKind
+
+	switch {
+	case opts.View != "":
+		var ok bool
+		if v, isViewer := p.(Viewer); isViewer {
+			res.URL, ok = v.ViewURL(opts.View, res.Repo, res.Ref, res.Path, res.LineStart, res.LineEnd)
+		}
+		if !ok {
+			return Result{}, fmt.Errorf("the %s view is not supported by %s", opts.View, p.BaseURL())
+		}
+	case res.Type == Commit:
+		res.URL = p.CommitURL(res.Repo, res.Commit)
+	case res.Type == Root:
+		res.URL = p.RootURL(res.Repo)
+	case heading == "" || res.Path == "":
+		file := res.Path != "" && (res.LineStart > 0 || r.isFile(gitroot, res.Ref, res.Path))
+		res.URL = pathURL(p, file, res.Repo, res.Ref, res.Path, res.LineStart, res.LineEnd)
+	default:
+		h, ok := p.(HeadingLinker)
+		if !ok {
+			r.explainf("path: heading %q ignored, %s does not link to headings", heading, p.BaseURL())
+			res.URL = pathURL(p, true, res.Repo, res.Ref, res.Path, res.LineStart, res.LineEnd)
+			break
+		}
+		res.Anchor, err = headingAnchor(h.HeadingStyle(), gitroot, res.Path, heading)
+		if err != nil {
+			return Result{}, err
+		}
+		res.URL = h.HeadingURL(res.Repo, res.Ref, res.Path, res.Anchor)
 	}
 
-	host, repo, err := parseRepository(remote)
+	r.explainf("url: %s", res.URL)
+	return res, nil
+}
+
+// isFile reports whether path, relative to gitroot, is a file rather than a directory: on disk, or else in the
+// tree of ref with `git cat-file -t`. Paths in neither are directories.
+func (r *resolver) isFile(gitroot, ref, path string) bool {
+	if info, err := os.Stat(filepath.Join(gitroot, filepath.FromSlash(path))); err == nil {
+		r.explainf("path: %q is a %s", path, fileOrDirectory(!info.IsDir()))
+		return !info.IsDir()
+	}
+
+	t, err := gitw.ObjectType(r.ctx, gitroot, ref+":"+path)
+	if err != nil {
+		r.explainf("path: %q is not on disk or at %s, assuming a directory", path, ref)
+		return false
+	}
+	r.explainf("path: %q is a %s at %s", path, fileOrDirectory(t == "blob"), ref)
+	return t == "blob"
+}
+
+// fileOrDirectory returns `file` when file is true, or else `directory`
+func fileOrDirectory(file bool) string {
+	if file {
+		return "file"
+	}
+	return "directory"
+}
+
+// pathURL returns the URL of a path, with FileURL for files when p links to files apart from directories
+func pathURL(p Provider, file bool, repo, ref, path string, lstart, lend int) string {
+	if f, ok := p.(FileLinker); ok && file {
+		return f.FileURL(repo, ref, path, lstart, lend)
+	}
+	return p.PathURL(repo, ref, path, lstart, lend)
+}
+
+// gitRoot returns the root of the working tree, or the Git directory of a bare repository
+func (r *resolver) gitRoot() (string, error) {
+	gitroot, err := gitw.Toplevel(r.ctx, r.dir)
+	if err != nil {
+		// If toplevel fails, we might be in a bare repo
+		gitroot, err = gitw.AbsoluteGitDir(r.ctx, r.dir)
+		if err != nil {
+			r.explainf("git root: not found, not a working tree or a git directory")
+			return "", &Error{
+				Err:  ErrNotRepository,
+				Hint: "run git open from within a Git repository, or choose one with `git -C <path> open`",
+			}
+		}
+		r.explainf("git root: %s, the git directory, as there is no working tree like in a bare repository", gitroot)
+		return gitroot, nil
+	}
+	r.explainf("git root: %s", gitroot)
+	return gitroot, nil
+}
+
+// repository is a Git repository and the Provider hosting it
+type repository struct {
+	provider Provider
+	// remote is the URL of the remote
+	remote  string
+	host    string
+	repo    string
+	ref     string
+	refKind RefKind
+}
+
+// resolveRepository returns the repository, its Provider and reference for a provided Git repository.
+// remote names the remote, and ref overrides the current reference, unless empty.
+func (r *resolver) resolveRepository(gitroot, remote, ref string) (repository, error) {
+	remoteURL, currentRef, err := r.getRemoteRef(gitroot, remote)
+	if err != nil {
+		return repository{}, err
+	}
+	r.explainf("remote: %s", redactURL(remoteURL))
+	remoteURL = r.normalizeRemote(gitroot, remoteURL)
+	if ref == "" {
+		ref = currentRef
+		r.explainf("ref: %s", ref)
+	} else {
+		r.explainf("ref: %s, instead of the current %s", ref, currentRef)
+	}
+
+	host, repo, err := parseRepository(remoteURL)
 	if err != nil {
-		return "", err
+		return repository{}, err
 	}
+
+	candidates, invalid, disabled := providers(r.ctx, gitroot)
 	if host == "" {
-		return "", fmt.Errorf("local remotes are not supported")
+		// Local remotes, like mirrors and other clones, open on their web remote
+		if remoteURL, err = r.resolveLocalRemote(gitroot, remoteURL, candidates); err != nil {
+			return repository{}, err
+		}
+		if host, repo, err = parseRepository(remoteURL); err != nil {
+			return repository{}, err
+		}
 	}
+	r.explainf("repository: host %q, repo %q", host, repo)
 
-	providers := Providers()
+	web := isWebRemote(remoteURL)
+	if !web {
+		host = r.resolveSSHAlias(host)
+	}
+
+	for _, k := range disabled {
+		r.explainf("provider: %s, skipped, disabled in git config", k)
+	}
 
-	// Find the provider by exact host comparison.
+	// Find the provider matching the host, preferring the longest matching base path, then the first provider.
+	// Providers with host patterns are matched after every exact host.
 	var p Provider
-	for _, provider := range providers {
-		u, err := url.Parse(provider.BaseURL())
-		if err != nil {
+	var relRepo string
+	var patterns []Provider
+	best := 0
+	for _, provider := range candidates {
+		if _, _, ok := hostPatternOf(provider); ok {
+			patterns = append(patterns, provider)
 			continue
 		}
-		if u.Host == host {
-			p = provider
-			break
+		rel, specificity, err := matchProvider(provider, host, repo, web)
+		switch {
+		case err != nil:
+			r.explainf("provider: %s from %s, skipped, %v", provider.BaseURL(), provider.Source(), err)
+			continue
+		case isHostAlias(provider, host):
+			r.explainf("provider: %s from %s, matches host alias %q", provider.BaseURL(), provider.Source(), host)
+		case specificity > 0:
+			r.explainf("provider: %s from %s, matches host %q and path %q", provider.BaseURL(), provider.Source(), host, strings.TrimSuffix(repo, rel))
+		default:
+			r.explainf("provider: %s from %s, matches host %q", provider.BaseURL(), provider.Source(), host)
+		}
+		if p == nil || specificity > best {
+			p, relRepo, best = provider, rel, specificity
 		}
 	}
 
-	if len(p.BaseURL()) == 0 {
-		return "", fmt.Errorf("unable to find provider for: \"%s\"", host)
+	if p == nil && len(patterns) > 0 {
+		p, relRepo = r.matchHostPattern(patterns, host, repo, web)
 	}
 
-	var openURL string
-	switch t {
-	case Commit:
-		openURL = p.CommitURL(repo, arg)
-	case Path:
-		openURL = p.PathURL(repo, ref, arg, lstart, lend)
-	case Root:
-		openURL = p.RootURL(repo)
+	if p == nil {
+		for _, k := range invalid {
+			if sameHost(k, host, false) {
+				return repository{}, &Error{
+					Err:  fmt.Errorf("%w: %q", ErrInvalidConfig, k),
+					Hint: "fix the warnings above, see the [open] sections with `git config --get-regexp '^open\\.'`",
+				}
+			}
+		}
+		for _, k := range disabled {
+			if sameHost(k, host, false) {
+				return repository{}, &Error{
+					Err:  fmt.Errorf("%w: \"%s\"", ErrUnknownProvider, host),
+					Hint: fmt.Sprintf("the provider for %s is disabled, enable it with `git config --global --unset open.%s.enabled`", host, k),
+				}
+			}
+		}
+
+		// The port of SSH remotes is not the port of the web
+		webHost := host
+		if !web {
+			webHost = (&url.URL{Host: host}).Hostname()
+		}
+		return repository{}, &Error{
+			Err: fmt.Errorf("%w: \"%s\"", ErrUnknownProvider, host),
+			Hint: fmt.Sprintf("add a provider for %s to git config, like `git config --global open.https://%s.commitprefix commit` "+
+				"and `git config --global open.https://%s.pathprefix tree`", webHost, webHost, webHost),
+		}
 	}
 
-	return openURL, nil
+	if relRepo != repo {
+		r.explainf("repository: repo %q, relative to %s", relRepo, p.BaseURL())
+	}
+
+	kind := r.refKind(gitroot, ref)
+	return repository{provider: withRefKind(p, kind), remote: redactURL(remoteURL), host: host, repo: relRepo, ref: ref, refKind: kind}, nil
+}
+
+// matchHostPattern returns the first provider whose host pattern or base URL matches the hostname of host, with its base URL
+// on the host, and repo relative to its base path. Wildcard hosts with the most literal characters are matched
+// first, then the other patterns, each in the order they are defined.
+func (r *resolver) matchHostPattern(patterns []Provider, host, repo string, web bool) (Provider, string) {
+	slices.SortStableFunc(patterns, func(a, b Provider) int {
+		_, sa, _ := hostPatternOf(a)
+		_, sb, _ := hostPatternOf(b)
+		return sb - sa
+	})
+
+	hostname := (&url.URL{Host: host}).Hostname()
+	for _, provider := range patterns {
+		re, _, _ := hostPatternOf(provider)
+		if !matchesHostPattern(provider, hostname) {
+			r.explainf("provider: %s from %s, skipped, host pattern %q does not match", provider.BaseURL(), provider.Source(), re)
+			continue
+		}
+		matched := provider.(PrefixProvider).withHost(hostname)
+		rel, _, err := matchProvider(matched, host, repo, web)
+		if err != nil {
+			r.explainf("provider: %s from %s, skipped, %v", provider.BaseURL(), provider.Source(), err)
+			continue
+		}
+		r.explainf("provider: %s from %s, host pattern %q matches host %q, using %s", provider.BaseURL(), provider.Source(), re, host, matched.BaseURL())
+		return matched, rel
+	}
+	return nil, ""
+}
+
+// resolveSSHAlias returns the real host of an SSH host alias in the ssh config, like `github.com` for
+// `github-work`, keeping the port. Hosts that are not aliases are returned as they are.
+func (r *resolver) resolveSSHAlias(host string) string {
+	u := &url.URL{Host: host}
+	configPath := sshConfigPath()
+	hostname, ok := sshHostName(configPath, u.Hostname())
+	if !ok || strings.EqualFold(hostname, u.Hostname()) {
+		return host
+	}
+
+	r.explainf("ssh: host %q is an alias of %q in %s", u.Hostname(), hostname, configPath)
+	if port := u.Port(); port != "" {
+		return net.JoinHostPort(hostname, port)
+	}
+	return hostname
+}
+
+// isWebRemote reports whether the remote is an http or https URL, whose port is the port of the web server
+func isWebRemote(remote string) bool {
+	scheme, _, ok := strings.Cut(remote, "://")
+	return ok && (strings.EqualFold(scheme, "http") || strings.EqualFold(scheme, "https"))
+}
+
+// refKind returns whether ref is a branch, tag or commit. Refs that cannot be classified, like
+// branches only on the remote, are branches.
+func (r *resolver) refKind(gitroot, ref string) RefKind {
+	name, err := gitw.SymbolicFullName(r.ctx, gitroot, ref)
+	switch {
+	case err != nil && commitSHARegex.MatchString(ref):
+		r.explainf("ref kind: commit, %q is not a local ref but looks like a commit SHA", ref)
+		return CommitRef
+	case err != nil:
+		r.explainf("ref kind: branch, %q is not a local ref", ref)
+		return Branch
+	case name == "":
+		r.explainf("ref kind: commit")
+		return CommitRef
+	case strings.HasPrefix(name, "refs/tags/"):
+		r.explainf("ref kind: tag, %s", name)
+		return Tag
+	default:
+		r.explainf("ref kind: branch, %s", name)
+		return Branch
+	}
 }
 
-// parsePath returns the cleaned path, relative to the gitroot, and the parsed start and end line numbers
-func parsePath(path, gitroot string) (string, int, int, error) {
+// withRefKind returns p building URLs for refs of kind, when p is a RefKindLinker
+func withRefKind(p Provider, kind RefKind) Provider {
+	if k, ok := p.(RefKindLinker); ok {
+		return k.WithRefKind(kind)
+	}
+	return p
+}
+
+// parsePath returns the cleaned path, relative to the gitroot, and the parsed start and end line numbers.
+// A symbol suffix is resolved to the line range of the matching function.
+func (r *resolver) parsePath(path, gitroot string) (string, int, int, error) {
 	if path == "" {
 		return "", 0, 0, nil
 	}
 
 	var lstart, lend int
-	if stripped, start, end := stripLine(path); stripped != path {
+	var symbol string
+	if stripped, start, end, sym := stripLine(path); stripped != path {
 		// Prefer the literal, colon-suffixed argument when it names a real
 		// file or directory; otherwise treat the suffix as a line spec.
-		if _, statErr := os.Stat(path); statErr != nil {
-			path, lstart, lend = stripped, start, end
+		if _, statErr := os.Stat(r.abs(path)); statErr != nil {
+			path, lstart, lend, symbol = stripped, start, end, sym
 		}
 	}
-	path = filepath.Clean(path)
+	target := filepath.Clean(path)
+	path = r.abs(target)
 
 	info, err := os.Stat(path)
 	if err != nil && (os.IsNotExist(err) || ancestorIsFile(path)) {
-		return "", 0, 0, err
+		return "", 0, 0, r.pathNotFound(target, gitroot)
 	}
 	if err == nil && info.IsDir() {
-		lstart, lend = 0, 0
+		lstart, lend, symbol = 0, 0, ""
 	}
 
-	path, _ = filepath.Abs(path)
 	if resolved, err := filepath.EvalSymlinks(path); err == nil {
 		path = resolved
 	}
@@ -146,7 +633,10 @@ func parsePath(path, gitroot string) (string, int, int, error) {
 	// Check if path is within Git root
 	rel, err := filepath.Rel(gitroot, path)
 	if err != nil || strings.HasPrefix(rel, "..") {
-		return "", 0, 0, fmt.Errorf("path does not contain gitroot: %s; %s", path, gitroot)
+		return "", 0, 0, &Error{
+			Err:  fmt.Errorf("%w: %s", ErrPathOutsideRepo, path),
+			Hint: fmt.Sprintf("paths must be within the repository at %s", gitroot),
+		}
 	}
 
 	if rel == "." {
@@ -154,7 +644,30 @@ func parsePath(path, gitroot string) (string, int, int, error) {
 	}
 
 	// Convert all path separators to `/` and trim trailing `/`
-	return filepath.ToSlash(rel), lstart, lend, nil
+	rel = filepath.ToSlash(rel)
+
+	if symbol != "" {
+		lstart, lend, err = gitw.FuncRange(r.ctx, gitroot, rel, symbol)
+		if err != nil {
+			return "", 0, 0, fmt.Errorf("unable to find symbol %q in %s: %w", symbol, rel, err)
+		}
+	}
+
+	return rel, lstart, lend, nil
+}
+
+// warnUntracked warns when the path, relative to the gitroot, is untracked or ignored, as it won't exist on the remote
+func (r *resolver) warnUntracked(gitroot, rel string) {
+	files, err := gitw.LsFiles(r.ctx, gitroot, ":(literal)"+rel)
+	if err != nil || len(files) > 0 {
+		return
+	}
+
+	if gitw.IsIgnored(r.ctx, gitroot, rel) {
+		warnf("%s is ignored by git and won't exist on the remote", rel)
+		return
+	}
+	warnf("%s is untracked and won't exist on the remote", rel)
 }
 
 // ancestorIsFile reports whether path is invalid directory because an ancestor
@@ -174,57 +687,82 @@ func ancestorIsFile(path string) bool {
 	}
 }
 
-// getRemoteRef returns the Git remote and reference (branch, tag, commit), for a provided Git repository
-func getRemoteRef(gitroot string) (remote string, ref string, err error) {
-	remote, err = gitw.RemoteURL(gitroot)
+// getRemoteRef returns the URL of the named Git remote, or the default remote when name is empty,
+// and the current reference (branch, tag, commit), for a provided Git repository
+func (r *resolver) getRemoteRef(gitroot, name string) (remote string, ref string, err error) {
+	remotes, _ := gitw.Remotes(r.ctx, gitroot)
+	switch {
+	case len(remotes) == 0:
+		return "", "", &Error{
+			Err:  ErrNoRemote,
+			Hint: "add the remote of the hosted repository with `git remote add origin <url>`",
+		}
+	case name != "" && !slices.Contains(remotes, name):
+		return "", "", &Error{
+			Err:  fmt.Errorf("%w: %q", ErrNoRemote, name),
+			Hint: fmt.Sprintf("choose one of the remotes: %s", strings.Join(remotes, ", ")),
+		}
+	}
+
+	remote, err = gitw.RemoteURL(r.ctx, gitroot, name)
 	if err != nil {
-		return "", "", err
+		return "", "", fmt.Errorf("unable to get remote url: %w", redactError(err))
 	}
 
-	ref, err = gitw.CurrentRef(gitroot)
+	ref, err = gitw.CurrentRef(r.ctx, gitroot)
 	return remote, ref, err
 }
 
-// parseRepository parses the host and repository (username or organization and repository name) from a remote string
+// parseRepository parses the host and repository (username or organization and repository name) from a remote string.
+// The userinfo and query of the remote, which may hold credentials, are never part of either.
 func parseRepository(remote string) (host string, repo string, err error) {
-	url, err := get.ParseURL(remote)
+	u, err := get.ParseURL(remote)
 	if err != nil {
-		return "", "", fmt.Errorf("unable to parse remote url: %w", err)
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return "", "", fmt.Errorf("unable to parse remote url: %s: %w", redactURL(remote), err)
 	}
 
-	repo = strings.TrimPrefix(strings.TrimSuffix(path.Clean(url.Path), ".git"), "/")
-	return url.Host, repo, nil
+	repo = strings.TrimPrefix(strings.TrimSuffix(path.Clean(u.Path), ".git"), "/")
+	return u.Host, repo, nil
 }
 
 // lineSuffixRegex matches trailing line numbers
 var lineSuffixRegex = regexp.MustCompile(`^[0-9-]+$`)
 
-// stripLine splits a trailing line suffix from arg, returning the path and line numbers
-func stripLine(arg string) (path string, start int, end int) {
+// stripLine splits a trailing line suffix like `:3` or `:3-10`, or a symbol suffix like `::parsePath`,
+// from arg, returning the path and line numbers or symbol
+func stripLine(arg string) (path string, start int, end int, symbol string) {
+	if i := strings.Index(arg, "::"); i > 0 && i+2 < len(arg) {
+		return arg[:i], 0, 0, arg[i+2:]
+	}
+
 	i := strings.LastIndex(arg, ":")
 	if i <= 0 {
-		return arg, 0, 0
+		return arg, 0, 0, ""
 	}
 	path, suffix := arg[:i], arg[i+1:]
 	if !lineSuffixRegex.MatchString(suffix) {
-		return arg, 0, 0
+		return arg, 0, 0, ""
 	}
 
 	before, after, isRange := strings.Cut(suffix, "-")
 
 	start, err := strconv.Atoi(before)
 	if err != nil || start < 1 {
-		return path, 0, 0
+		return path, 0, 0, ""
 	}
 	if !isRange {
-		return path, start, 0
+		return path, start, 0, ""
 	}
 
 	end, err = strconv.Atoi(after)
 	if err != nil || end < 1 {
-		return path, start, 0
+		return path, start, 0, ""
 	}
-	return path, start, end
+	return path, start, end, ""
 }
 
 var commitSHARegex = regexp.MustCompile(`^[0-9a-f]{7,64}$`)
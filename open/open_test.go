@@ -1,9 +1,10 @@
 package open
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -45,7 +46,7 @@ func TestGetURL(t *testing.T) {
 		},
 		"file": {
 			arg:         "open_test.go",
-			expectedURL: "https://github.com/arbourd/git-open/tree/%s/open/open_test.go",
+			expectedURL: "https://github.com/arbourd/git-open/blob/%s/open/open_test.go",
 		},
 		"commit sha": {
 			arg:         "7605d91",
@@ -53,53 +54,73 @@ func TestGetURL(t *testing.T) {
 		},
 		"hex-named file": {
 			arg:         "abcdef1",
-			expectedURL: "https://github.com/arbourd/git-open/tree/%s/open/abcdef1",
+			expectedURL: "https://github.com/arbourd/git-open/blob/%s/open/abcdef1",
 		},
 		"commit sha with extension": {
-			arg:         "7605d91.txt",
-			expectedURL: "https://github.com/arbourd/git-open/tree/%s",
+			arg:     "7605d91.txt",
+			wantErr: true,
 		},
 		"commit sha as a folder": {
-			arg:         "7605d91/example.txt",
-			expectedURL: "https://github.com/arbourd/git-open/tree/%s",
+			arg:     "7605d91/example.txt",
+			wantErr: true,
 		},
 		"out of git dir relative path": {
-			arg:         filepath.FromSlash("../../.."),
-			expectedURL: "https://github.com/arbourd/git-open/tree/%s",
+			arg:     filepath.FromSlash("../../.."),
+			wantErr: true,
 		},
 		"out of git dir absolute path": {
-			arg:         filepath.FromSlash(homedir),
-			expectedURL: "https://github.com/arbourd/git-open/tree/%s",
+			arg:     filepath.FromSlash(homedir),
+			wantErr: true,
 		},
 		"file with line": {
 			arg:         "open_test.go:3",
-			expectedURL: "https://github.com/arbourd/git-open/tree/%s/open/open_test.go#L3",
+			expectedURL: "https://github.com/arbourd/git-open/blob/%s/open/open_test.go#L3",
 		},
 		"file with line range": {
 			arg:         "open_test.go:3-10",
-			expectedURL: "https://github.com/arbourd/git-open/tree/%s/open/open_test.go#L3-L10",
+			expectedURL: "https://github.com/arbourd/git-open/blob/%s/open/open_test.go#L3-L10",
 		},
 		"file with line zero drops the line, keeps the file": {
 			arg:         "open_test.go:0",
-			expectedURL: "https://github.com/arbourd/git-open/tree/%s/open/open_test.go",
+			expectedURL: "https://github.com/arbourd/git-open/blob/%s/open/open_test.go",
 		},
 		"file with reversed line range": {
 			arg:         "open_test.go:10-3",
-			expectedURL: "https://github.com/arbourd/git-open/tree/%s/open/open_test.go#L10-L3",
+			expectedURL: "https://github.com/arbourd/git-open/blob/%s/open/open_test.go#L10-L3",
 		},
-		"non-numeric suffix is not a line spec, no literal match falls back to root": {
-			arg:         "open_test.go:abc",
-			expectedURL: "https://github.com/arbourd/git-open/tree/%s",
+		"non-numeric suffix is not a line spec, no literal match": {
+			arg:     "open_test.go:abc",
+			wantErr: true,
 		},
-		"literal colon filename with no matching file falls back to root": {
-			arg:         "notes:42",
-			expectedURL: "https://github.com/arbourd/git-open/tree/%s",
+		"literal colon filename with no matching file": {
+			arg:     "notes:42",
+			wantErr: true,
+		},
+		"markdown heading": {
+			arg:         filepath.FromSlash("../README.md#providers"),
+			expectedURL: "https://github.com/arbourd/git-open/blob/%s/README.md#providers",
+		},
+		"markdown heading by text": {
+			arg:         filepath.FromSlash("../README.md#Providers"),
+			expectedURL: "https://github.com/arbourd/git-open/blob/%s/README.md#providers",
+		},
+		"markdown heading that does not exist": {
+			arg:     filepath.FromSlash("../README.md#does-not-exist"),
+			wantErr: true,
+		},
+		"heading in a file that is not markdown": {
+			arg:     "open.go#providers",
+			wantErr: true,
+		},
+		"markdown file with line shows source": {
+			arg:         filepath.FromSlash("../README.md:3"),
+			expectedURL: "https://github.com/arbourd/git-open/blob/%s/README.md?plain=1#L3",
 		},
 	}
 
 	for name, c := range cases {
 		t.Run(name, func(t *testing.T) {
-			ref, err := gitw.CurrentRef(c.gitdir)
+			ref, err := gitw.CurrentRef(t.Context(), c.gitdir)
 			if err != nil {
 				t.Fatalf("Unable to get local ref for test: %v", err)
 			}
@@ -121,51 +142,78 @@ func TestGetURL(t *testing.T) {
 	}
 }
 
-func TestGetURLErrors(t *testing.T) {
-	git := func(t *testing.T, dir string, args ...string) {
-		t.Helper()
-		cmd := exec.Command("git", args...)
-		cmd.Dir = dir
-		if out, err := cmd.CombinedOutput(); err != nil {
-			t.Fatalf("git %v: %v\n%s", args, err, out)
-		}
+func TestUnknownProviderHint(t *testing.T) {
+	cases := map[string]struct {
+		remote       string
+		expectedHint string
+	}{
+		"ssh remote on a port": {
+			remote:       "ssh://git@git.example.com:2222/team/repo.git",
+			expectedHint: "add a provider for git.example.com to git config, like `git config --global open.https://git.example.com.commitprefix commit` and `git config --global open.https://git.example.com.pathprefix tree`",
+		},
+		"https remote on a port": {
+			remote:       "https://git.example.com:8443/team/repo.git",
+			expectedHint: "add a provider for git.example.com:8443 to git config, like `git config --global open.https://git.example.com:8443.commitprefix commit` and `git config --global open.https://git.example.com:8443.pathprefix tree`",
+		},
 	}
 
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Resolve(t.Context(), Options{RepoDir: initRepo(t, c.remote)})
+			if !errors.Is(err, ErrUnknownProvider) {
+				t.Fatalf("unexpected error:\n\t(GOT): %#v\n\t(WNT): errors.Is %#v", err, ErrUnknownProvider)
+			}
+			if hint := Hint(err); hint != c.expectedHint {
+				t.Fatalf("unexpected hint:\n\t(GOT): %#v\n\t(WNT): %#v", hint, c.expectedHint)
+			}
+		})
+	}
+}
+
+func TestGetURLErrors(t *testing.T) {
 	cases := map[string]struct {
-		setup   func(t *testing.T, dir string)
+		setup   func(t *testing.T) string
 		wantErr string
+		wantIs  error
 	}{
 		"not a git repository": {
-			setup:   func(t *testing.T, dir string) {},
+			setup:   func(t *testing.T) string { return t.TempDir() },
 			wantErr: "not a git repository",
+			wantIs:  ErrNotRepository,
+		},
+		"no remote": {
+			setup:   func(t *testing.T) string { return initRepo(t, "") },
+			wantErr: "no remote configured",
+			wantIs:  ErrNoRemote,
 		},
 		"local remote": {
-			setup: func(t *testing.T, dir string) {
-				git(t, dir, "init")
-				git(t, dir, "config", "user.email", "test@example.com")
-				git(t, dir, "config", "user.name", "Test")
-				git(t, dir, "remote", "add", "origin", filepath.Join(t.TempDir(), "local", "repo"))
-				git(t, dir, "commit", "--allow-empty", "-m", "init")
+			setup: func(t *testing.T) string {
+				return initRepo(t, filepath.Join(t.TempDir(), "local", "repo"))
 			},
 			wantErr: "local remotes are not supported",
+			wantIs:  ErrLocalRemote,
 		},
 		"unsupported provider": {
-			setup: func(t *testing.T, dir string) {
-				git(t, dir, "init")
-				git(t, dir, "config", "user.email", "test@example.com")
-				git(t, dir, "config", "user.name", "Test")
-				git(t, dir, "remote", "add", "origin", "https://unknown.example.com/user/repo.git")
-				git(t, dir, "commit", "--allow-empty", "-m", "init")
+			setup: func(t *testing.T) string {
+				return initRepo(t, "https://unknown.example.com/user/repo.git")
 			},
 			wantErr: `unable to find provider for: "unknown.example.com"`,
+			wantIs:  ErrUnknownProvider,
+		},
+		"invalid provider in git config": {
+			setup: func(t *testing.T) string {
+				dir := initRepo(t, "https://invalid.example.com/user/repo.git")
+				runGit(t, dir, "config", "open.https://invalid.example.com.commitprefix", "commit")
+				return dir
+			},
+			wantErr: `invalid provider in git config: "https://invalid.example.com"`,
+			wantIs:  ErrInvalidConfig,
 		},
 	}
 
 	for name, c := range cases {
 		t.Run(name, func(t *testing.T) {
-			dir := t.TempDir()
-			c.setup(t, dir)
-			t.Chdir(dir)
+			t.Chdir(c.setup(t))
 
 			_, err := GetURL("")
 			if err == nil {
@@ -174,30 +222,293 @@ func TestGetURLErrors(t *testing.T) {
 			if !strings.Contains(err.Error(), c.wantErr) {
 				t.Fatalf("unexpected error:\n\t(GOT): %q\n\t(WNT): contains %q", err.Error(), c.wantErr)
 			}
+			if !errors.Is(err, c.wantIs) {
+				t.Fatalf("unexpected error:\n\t(GOT): %#v\n\t(WNT): errors.Is %#v", err, c.wantIs)
+			}
+			if Hint(err) == "" {
+				t.Fatalf("expected hint for error: %q", err.Error())
+			}
 		})
 	}
 }
 
-func TestGetURLBareRepo(t *testing.T) {
-	mainDir := t.TempDir()
-	bareDir := t.TempDir()
+func TestResolveLenient(t *testing.T) {
+	ref, err := gitw.CurrentRef(t.Context(), ".")
+	if err != nil {
+		t.Fatalf("unable to get local ref for test: %v", err)
+	}
+	expectedURL := fmt.Sprintf("https://github.com/arbourd/git-open/tree/%s", ref)
+
+	cases := map[string]struct {
+		arg string
+	}{
+		"file does not exist": {
+			arg: "7605d91.txt",
+		},
+		"out of git dir relative path": {
+			arg: filepath.FromSlash("../../.."),
+		},
+		"non-numeric suffix is not a line spec": {
+			arg: "open_test.go:abc",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			res, err := Resolve(t.Context(), Options{Target: c.arg, Lenient: true})
+			if err != nil {
+				t.Fatalf("unexpected error:\n\t(GOT): %#v\n\t(WNT): nil", err)
+			}
+			if res.URL != expectedURL {
+				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", res.URL, expectedURL)
+			}
+		})
+	}
+}
+
+func TestGetURLPathNotFound(t *testing.T) {
+	dir := initRepo(t, "https://github.com/example/repo.git")
 
-	run := func(dir string, args ...string) {
-		t.Helper()
-		cmd := exec.Command("git", args...)
-		cmd.Dir = dir
-		if out, err := cmd.CombinedOutput(); err != nil {
-			t.Fatalf("git %v: %v\n%s", args, err, out)
+	if err := os.MkdirAll(filepath.Join(dir, "cmd", "server"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"main.go", "README.md", filepath.Join("cmd", "server", "server.go")} {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "init")
+
+	cases := map[string]struct {
+		cwd          string
+		arg          string
+		expectedHint string
+	}{
+		"typo in file": {
+			arg:          "mian.go",
+			expectedHint: `did you mean "main.go"?`,
+		},
+		"typo in directory": {
+			arg:          filepath.FromSlash("cmd/sever/server.go"),
+			expectedHint: fmt.Sprintf("did you mean %q?", filepath.FromSlash("cmd/server/server.go")),
+		},
+		"file in another directory": {
+			arg:          "sever.go",
+			expectedHint: fmt.Sprintf("did you mean %q?", filepath.FromSlash("cmd/server/server.go")),
+		},
+		"relative to the current directory": {
+			cwd:          "cmd",
+			arg:          filepath.FromSlash("../READNE.md"),
+			expectedHint: fmt.Sprintf("did you mean %q?", filepath.FromSlash("../README.md")),
+		},
+		"nothing close": {
+			arg:          "database.sql",
+			expectedHint: "",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Chdir(filepath.Join(dir, c.cwd))
+
+			_, err := GetURL(c.arg)
+			if !errors.Is(err, ErrPathNotFound) {
+				t.Fatalf("unexpected error:\n\t(GOT): %#v\n\t(WNT): errors.Is %#v", err, ErrPathNotFound)
+			}
+			if hint := Hint(err); hint != c.expectedHint {
+				t.Fatalf("unexpected hint:\n\t(GOT): %#v\n\t(WNT): %#v", hint, c.expectedHint)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir := initRepo(t, "https://github.com/example/fork.git")
+
+	for _, d := range []string{"docs", "dir with space"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		"main.go":                         "package main\n\nfunc main() {\n}\n",
+		"1234567":                         "",
+		filepath.Join("docs", "guide.md"): "# Guide\n\n## Getting started\n",
+		filepath.Join("dir with space", "f#1.txt"): "one\ntwo\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
 		}
 	}
 
-	run(mainDir, "init")
-	run(mainDir, "config", "user.email", "test@example.com")
-	run(mainDir, "config", "user.name", "Test")
-	run(mainDir, "remote", "add", "origin", "https://github.com/example/repo.git")
-	run(mainDir, "commit", "--allow-empty", "-m", "init")
-	run(mainDir, "clone", "--bare", mainDir, bareDir)
-	run(bareDir, "remote", "set-url", "origin", "https://github.com/example/repo.git")
+	runGit(t, dir, "remote", "add", "upstream", "https://gitlab.com/example/repo.git")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "init")
+	runGit(t, dir, "tag", "v1.0.0")
+
+	// The process stays in the package directory, so only RepoDir locates the repository
+	github := Result{Provider: "https://github.com", Remote: "https://github.com/example/fork.git", Host: "github.com", Repo: "example/fork", Ref: "main", RefKind: Branch}
+	with := func(res Result, f func(*Result)) Result {
+		f(&res)
+		return res
+	}
+
+	cases := map[string]struct {
+		opts           Options
+		expectedResult Result
+		wantErr        error
+	}{
+		"root": {
+			opts: Options{RepoDir: dir},
+			expectedResult: with(github, func(r *Result) {
+				r.URL, r.Type = "https://github.com/example/fork", Root
+			}),
+		},
+		"path relative to the repository directory": {
+			opts: Options{RepoDir: filepath.Join(dir, "docs"), Target: "guide.md"},
+			expectedResult: with(github, func(r *Result) {
+				r.URL, r.Type, r.Path = "https://github.com/example/fork/blob/main/docs/guide.md", Path, "docs/guide.md"
+			}),
+		},
+		"directory": {
+			opts: Options{RepoDir: dir, Target: "docs"},
+			expectedResult: with(github, func(r *Result) {
+				r.URL, r.Type, r.Path = "https://github.com/example/fork/tree/main/docs", Path, "docs"
+			}),
+		},
+		"path with lines": {
+			opts: Options{RepoDir: dir, Target: "main.go:3-4"},
+			expectedResult: with(github, func(r *Result) {
+				r.URL, r.Type, r.Path, r.LineStart, r.LineEnd = "https://github.com/example/fork/blob/main/main.go#L3-L4", Path, "main.go", 3, 4
+			}),
+		},
+		"heading": {
+			opts: Options{RepoDir: dir, Target: filepath.FromSlash("docs/guide.md#getting-started")},
+			expectedResult: with(github, func(r *Result) {
+				r.URL, r.Type, r.Path, r.Anchor = "https://github.com/example/fork/blob/main/docs/guide.md#getting-started", Path, "docs/guide.md", "getting-started"
+			}),
+		},
+		"file name with a # and a line": {
+			opts: Options{RepoDir: dir, Target: filepath.FromSlash("dir with space/f#1.txt:1")},
+			expectedResult: with(github, func(r *Result) {
+				r.URL, r.Type, r.Path, r.LineStart = "https://github.com/example/fork/blob/main/dir%20with%20space/f%231.txt#L1", Path, "dir with space/f#1.txt", 1
+			}),
+		},
+		"commit": {
+			opts: Options{RepoDir: dir, Target: "7605d91"},
+			expectedResult: with(github, func(r *Result) {
+				r.URL, r.Type, r.Commit = "https://github.com/example/fork/commit/7605d91", Commit, "7605d91"
+			}),
+		},
+		"file that looks like a commit": {
+			opts: Options{RepoDir: dir, Target: "1234567"},
+			expectedResult: with(github, func(r *Result) {
+				r.URL, r.Type, r.Path = "https://github.com/example/fork/blob/main/1234567", Path, "1234567"
+			}),
+		},
+		"forced commit type": {
+			opts: Options{RepoDir: dir, Target: "1234567", Type: Commit},
+			expectedResult: with(github, func(r *Result) {
+				r.URL, r.Type, r.Commit = "https://github.com/example/fork/commit/1234567", Commit, "1234567"
+			}),
+		},
+		"named remote": {
+			opts: Options{RepoDir: dir, Remote: "upstream"},
+			expectedResult: Result{
+				URL: "https://gitlab.com/example/repo", Type: Root, Provider: "https://gitlab.com",
+				Remote: "https://gitlab.com/example/repo.git", Host: "gitlab.com", Repo: "example/repo", Ref: "main", RefKind: Branch,
+			},
+		},
+		"ref": {
+			opts: Options{RepoDir: dir, Target: "main.go", Ref: "v1.0.0"},
+			expectedResult: with(github, func(r *Result) {
+				r.URL, r.Type, r.Path, r.Ref, r.RefKind = "https://github.com/example/fork/blob/v1.0.0/main.go", Path, "main.go", "v1.0.0", Tag
+			}),
+		},
+		"ref not in the repository": {
+			opts: Options{RepoDir: dir, Target: "main.go", Ref: "feature"},
+			expectedResult: with(github, func(r *Result) {
+				r.URL, r.Type, r.Path, r.Ref = "https://github.com/example/fork/blob/feature/main.go", Path, "main.go", "feature"
+			}),
+		},
+		"commit not in the repository": {
+			opts: Options{RepoDir: dir, Target: "main.go", Ref: "7605d91"},
+			expectedResult: with(github, func(r *Result) {
+				r.URL, r.Type, r.Path, r.Ref, r.RefKind = "https://github.com/example/fork/blob/7605d91/main.go", Path, "main.go", "7605d91", CommitRef
+			}),
+		},
+		"blame view with lines": {
+			opts: Options{RepoDir: dir, Target: "main.go:3", View: Blame},
+			expectedResult: with(github, func(r *Result) {
+				r.URL, r.Type, r.View, r.Path, r.LineStart = "https://github.com/example/fork/blame/main/main.go#L3", Path, Blame, "main.go", 3
+			}),
+		},
+		"history view of a directory": {
+			opts: Options{RepoDir: dir, Target: "docs", View: History},
+			expectedResult: with(github, func(r *Result) {
+				r.URL, r.Type, r.View, r.Path = "https://github.com/example/fork/commits/main/docs", Path, History, "docs"
+			}),
+		},
+		"raw view of a directory": {
+			opts:    Options{RepoDir: dir, Target: "docs", View: Raw},
+			wantErr: errors.New("the raw view is only supported for files"),
+		},
+		"view of a commit": {
+			opts:    Options{RepoDir: dir, Target: "7605d91", View: History},
+			wantErr: errors.New("the history view is only supported for paths"),
+		},
+		"unknown view": {
+			opts:    Options{RepoDir: dir, View: "source"},
+			wantErr: errors.New(`unknown view: "source"`),
+		},
+		"unknown remote": {
+			opts:    Options{RepoDir: dir, Remote: "fork"},
+			wantErr: ErrNoRemote,
+		},
+		"not a repository": {
+			opts:    Options{RepoDir: t.TempDir()},
+			wantErr: ErrNotRepository,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			res, err := Resolve(t.Context(), c.opts)
+			if c.wantErr != nil {
+				if err == nil || (!errors.Is(err, c.wantErr) && err.Error() != c.wantErr.Error()) {
+					t.Fatalf("unexpected error:\n\t(GOT): %#v\n\t(WNT): %#v", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res != c.expectedResult {
+				t.Fatalf("unexpected result:\n\t(GOT): %#v\n\t(WNT): %#v", res, c.expectedResult)
+			}
+		})
+	}
+}
+
+func TestResolveCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err := Resolve(ctx, Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error:\n\t(GOT): %#v\n\t(WNT): %#v", err, context.Canceled)
+	}
+}
+
+func TestGetURLBareRepo(t *testing.T) {
+	mainDir := initRepo(t, "https://github.com/example/repo.git")
+	bareDir := t.TempDir()
+
+	runGit(t, mainDir, "clone", "--bare", mainDir, bareDir)
+	runGit(t, bareDir, "remote", "set-url", "origin", "https://github.com/example/repo.git")
 
 	t.Chdir(bareDir)
 
@@ -211,24 +522,10 @@ func TestGetURLBareRepo(t *testing.T) {
 }
 
 func TestGetURLWorktree(t *testing.T) {
-	mainDir := t.TempDir()
+	mainDir := initRepo(t, "https://github.com/example/repo.git")
 	worktreeDir := filepath.Join(t.TempDir(), "wt")
 
-	run := func(args ...string) {
-		t.Helper()
-		cmd := exec.Command("git", args...)
-		cmd.Dir = mainDir
-		if out, err := cmd.CombinedOutput(); err != nil {
-			t.Fatalf("git %v: %v\n%s", args, err, out)
-		}
-	}
-
-	run("init")
-	run("config", "user.email", "test@example.com")
-	run("config", "user.name", "Test")
-	run("remote", "add", "origin", "https://github.com/example/repo.git")
-	run("commit", "--allow-empty", "-m", "init")
-	run("worktree", "add", "--detach", worktreeDir)
+	runGit(t, mainDir, "worktree", "add", "--detach", worktreeDir)
 
 	if err := os.WriteFile(filepath.Join(worktreeDir, "file.txt"), []byte{}, 0644); err != nil {
 		t.Fatal(err)
@@ -236,7 +533,7 @@ func TestGetURLWorktree(t *testing.T) {
 
 	t.Chdir(worktreeDir)
 
-	ref, err := gitw.CurrentRef(worktreeDir)
+	ref, err := gitw.CurrentRef(t.Context(), worktreeDir)
 	if err != nil {
 		t.Fatalf("unable to get ref: %v", err)
 	}
@@ -251,7 +548,7 @@ func TestGetURLWorktree(t *testing.T) {
 		},
 		"path": {
 			arg:         "file.txt",
-			expectedURL: fmt.Sprintf("https://github.com/example/repo/tree/%s/file.txt", ref),
+			expectedURL: fmt.Sprintf("https://github.com/example/repo/blob/%s/file.txt", ref),
 		},
 	}
 
@@ -273,7 +570,7 @@ func TestGetURLWindowsAbsolutePath(t *testing.T) {
 		t.Skip("windows drive-letter path parsing only applies on windows")
 	}
 
-	ref, err := gitw.CurrentRef(".")
+	ref, err := gitw.CurrentRef(t.Context(), ".")
 	if err != nil {
 		t.Fatalf("unable to get local ref for test: %v", err)
 	}
@@ -288,12 +585,140 @@ func TestGetURLWindowsAbsolutePath(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	expectedURL := fmt.Sprintf("https://github.com/arbourd/git-open/tree/%s/open/open_test.go#L3-L10", ref)
+	expectedURL := fmt.Sprintf("https://github.com/arbourd/git-open/blob/%s/open/open_test.go#L3-L10", ref)
 	if url != expectedURL {
 		t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", url, expectedURL)
 	}
 }
 
+func TestResolveRefKinds(t *testing.T) {
+	resetRegistry(t)
+
+	p, err := NewProvider(ProviderConfig{BaseURL: "https://forgejo.example.dev", Type: "forgejo"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Register(p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := map[string]struct {
+		remote string
+		// ref returns the ref to open, running git in the repository
+		ref         func(git func(args ...string) string) string
+		expectedURL string
+	}{
+		"codeberg branch": {
+			remote:      "https://codeberg.org/team/repo.git",
+			ref:         func(git func(args ...string) string) string { return "main" },
+			expectedURL: "https://codeberg.org/team/repo/src/branch/main/docs",
+		},
+		"codeberg tag": {
+			remote: "https://codeberg.org/team/repo.git",
+			ref: func(git func(args ...string) string) string {
+				git("tag", "v1.0.0")
+				return "v1.0.0"
+			},
+			expectedURL: "https://codeberg.org/team/repo/src/tag/v1.0.0/docs",
+		},
+		"codeberg commit": {
+			remote: "https://codeberg.org/team/repo.git",
+			ref: func(git func(args ...string) string) string {
+				return git("rev-parse", "HEAD")
+			},
+			expectedURL: "https://codeberg.org/team/repo/src/commit/%s/docs",
+		},
+		"self-hosted forgejo tag": {
+			remote: "git@forgejo.example.dev:team/repo.git",
+			ref: func(git func(args ...string) string) string {
+				git("tag", "v2.0.0")
+				return "v2.0.0"
+			},
+			expectedURL: "https://forgejo.example.dev/team/repo/src/tag/v2.0.0/docs",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := initRepo(t, c.remote)
+			if err := os.Mkdir(filepath.Join(dir, "docs"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			ref := c.ref(func(args ...string) string { return runGit(t, dir, args...) })
+			expectedURL := c.expectedURL
+			if strings.Contains(expectedURL, "%s") {
+				expectedURL = fmt.Sprintf(expectedURL, ref)
+			}
+
+			res, err := Resolve(t.Context(), Options{RepoDir: dir, Target: "docs", Ref: ref})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.URL != expectedURL {
+				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", res.URL, expectedURL)
+			}
+		})
+	}
+}
+
+func TestIsFile(t *testing.T) {
+	dir := initRepo(t, "")
+
+	for _, name := range []string{"main.go", filepath.Join("docs", "guide.md"), filepath.Join("old", "old.go")} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package main\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "init")
+	if err := os.RemoveAll(filepath.Join(dir, "old")); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := newResolver(t.Context(), dir, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := map[string]struct {
+		path         string
+		expectedFile bool
+	}{
+		"file on disk": {
+			path:         "main.go",
+			expectedFile: true,
+		},
+		"directory on disk": {
+			path:         "docs",
+			expectedFile: false,
+		},
+		"file only at the ref": {
+			path:         "old/old.go",
+			expectedFile: true,
+		},
+		"directory only at the ref": {
+			path:         "old",
+			expectedFile: false,
+		},
+		"unknown path": {
+			path:         "does/not/exist.go",
+			expectedFile: false,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if file := r.isFile(dir, "main", c.path); file != c.expectedFile {
+				t.Fatalf("unexpected file:\n\t(GOT): %#v\n\t(WNT): %#v", file, c.expectedFile)
+			}
+		})
+	}
+}
+
 func TestParseRepository(t *testing.T) {
 	cases := map[string]struct {
 		remote       string
@@ -409,10 +834,11 @@ func TestParseType(t *testing.T) {
 
 func TestStripLine(t *testing.T) {
 	cases := map[string]struct {
-		arg           string
-		expectedPath  string
-		expectedStart int
-		expectedEnd   int
+		arg            string
+		expectedPath   string
+		expectedStart  int
+		expectedEnd    int
+		expectedSymbol string
 	}{
 		"no line suffix": {
 			arg:          "main.go",
@@ -512,21 +938,49 @@ func TestStripLine(t *testing.T) {
 			expectedStart: 5,
 			expectedEnd:   5,
 		},
+		"symbol": {
+			arg:            "main.go::processArgs",
+			expectedPath:   "main.go",
+			expectedSymbol: "processArgs",
+		},
+		"nested path with symbol": {
+			arg:            "a/b/main.go::processArgs",
+			expectedPath:   "a/b/main.go",
+			expectedSymbol: "processArgs",
+		},
+		"qualified symbol keeps its colons": {
+			arg:            "main.cc::Foo::bar",
+			expectedPath:   "main.cc",
+			expectedSymbol: "Foo::bar",
+		},
+		"empty symbol falls back to full arg": {
+			arg:          "main.go::",
+			expectedPath: "main.go::",
+		},
+		"no path before symbol falls back to full arg": {
+			arg:          "::main",
+			expectedPath: "::main",
+		},
+		"windows absolute path with symbol": {
+			arg:            `C:\Example\main.go::processArgs`,
+			expectedPath:   `C:\Example\main.go`,
+			expectedSymbol: "processArgs",
+		},
 	}
 
 	for name, c := range cases {
 		t.Run(name, func(t *testing.T) {
-			path, start, end := stripLine(c.arg)
-			if path != c.expectedPath || start != c.expectedStart || end != c.expectedEnd {
-				t.Fatalf("unexpected result:\n\t(GOT): path=%#v start=%#v end=%#v\n\t(WNT): path=%#v start=%#v end=%#v",
-					path, start, end, c.expectedPath, c.expectedStart, c.expectedEnd)
+			path, start, end, symbol := stripLine(c.arg)
+			if path != c.expectedPath || start != c.expectedStart || end != c.expectedEnd || symbol != c.expectedSymbol {
+				t.Fatalf("unexpected result:\n\t(GOT): path=%#v start=%#v end=%#v symbol=%#v\n\t(WNT): path=%#v start=%#v end=%#v symbol=%#v",
+					path, start, end, symbol, c.expectedPath, c.expectedStart, c.expectedEnd, c.expectedSymbol)
 			}
 		})
 	}
 }
 
 func TestParsePath(t *testing.T) {
-	gitroot, err := gitw.Toplevel(".")
+	gitroot, err := gitw.Toplevel(t.Context(), ".")
 	if err != nil {
 		panic("not a git repository")
 	}
@@ -586,11 +1040,25 @@ func TestParsePath(t *testing.T) {
 			path:         filepath.FromSlash("../open:5"),
 			expectedPath: "open",
 		},
+		"directory with symbol is dropped": {
+			path:         filepath.FromSlash("../open::parsePath"),
+			expectedPath: "open",
+		},
+		"unknown symbol": {
+			path:         "open.go::doesNotExist",
+			expectedPath: "",
+			wantErr:      true,
+		},
 	}
 
 	for name, c := range cases {
 		t.Run(name, func(t *testing.T) {
-			path, start, end, err := parsePath(c.path, gitroot)
+			r, err := newResolver(t.Context(), "", nil)
+			if err != nil {
+				t.Fatalf("unable to create resolver: %v", err)
+			}
+
+			path, start, end, err := r.parsePath(c.path, gitroot)
 			if err != nil && !c.wantErr {
 				t.Fatalf("unexpected error:\n\t(GOT): %s\n\t(WNT): nil", err)
 			} else if err == nil && c.wantErr {
@@ -602,3 +1070,125 @@ func TestParsePath(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePathSymbol(t *testing.T) {
+	dir := initRepo(t, "")
+
+	src := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(hello())\n}\n\nfunc hello() string {\n\treturn \"hello\"\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "init")
+
+	t.Chdir(dir)
+	gitroot, err := gitw.Toplevel(t.Context(), ".")
+	if err != nil {
+		t.Fatalf("unable to get gitroot: %v", err)
+	}
+
+	r, err := newResolver(t.Context(), dir, nil)
+	if err != nil {
+		t.Fatalf("unable to create resolver: %v", err)
+	}
+
+	path, start, end, err := r.parsePath("main.go::hello", gitroot)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "main.go" || start != 9 || end != 11 {
+		t.Fatalf("unexpected result:\n\t(GOT): path=%#v start=%#v end=%#v\n\t(WNT): path=%#v start=%#v end=%#v",
+			path, start, end, "main.go", 9, 11)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	dir := initRepo(t, "https://gitlab.com/example/repo.git")
+
+	t.Chdir(dir)
+	gitroot, err := gitw.Toplevel(t.Context(), ".")
+	if err != nil {
+		t.Fatalf("unable to get gitroot: %v", err)
+	}
+
+	cases := map[string]struct {
+		arg      string
+		lenient  bool
+		expected []string
+	}{
+		"root": {
+			arg: "",
+			expected: []string{
+				"explain: type: root, no target was provided",
+				"explain: git root: " + gitroot,
+				"explain: remote: https://gitlab.com/example/repo.git",
+				"explain: ref: main",
+				`explain: repository: host "gitlab.com", repo "example/repo"`,
+				`explain: provider: https://github.com from built-in, skipped, host "github.com" does not match`,
+				`explain: provider: https://gitlab.com from built-in, matches host "gitlab.com"`,
+				"explain: url: https://gitlab.com/example/repo",
+			},
+		},
+		"commit": {
+			arg:      "7605d91",
+			expected: []string{`explain: type: commit, "7605d91" looks like a commit SHA and does not name a file`},
+		},
+		"path that does not exist": {
+			arg:     "missing.txt",
+			lenient: true,
+			expected: []string{
+				`explain: type: path, "missing.txt" does not look like a commit SHA`,
+				`explain: path: unable to resolve "missing.txt":`,
+				"falling back to the repository root",
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var b strings.Builder
+			if _, err := Resolve(t.Context(), Options{Target: c.arg, Lenient: c.lenient, Explain: &b}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, e := range c.expected {
+				if !strings.Contains(b.String(), e) {
+					t.Fatalf("unexpected explanation:\n\t(GOT): %s\n\t(WNT): contains %q", b.String(), e)
+				}
+			}
+		})
+	}
+}
+
+func TestExplainOverrides(t *testing.T) {
+	dir := initRepo(t, "https://gitlab.com/example/repo.git")
+
+	runGit(t, dir, "config", "open.https://github.com.enabled", "false")
+	runGit(t, dir, "config", "open.https://gitlab.com.pathprefix", "-/blob")
+	runGit(t, dir, "remote", "add", "upstream", "https://github.com/example/repo.git")
+
+	var b strings.Builder
+	res, err := Resolve(t.Context(), Options{RepoDir: dir, Target: ".", Explain: &b})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "https://gitlab.com/example/repo/-/blob/main"; res.URL != expected {
+		t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", res.URL, expected)
+	}
+	for _, e := range []string{
+		"explain: provider: https://github.com, skipped, disabled in git config",
+		`explain: provider: https://gitlab.com from git config (file:.git/config), over built-in, matches host "gitlab.com"`,
+	} {
+		if !strings.Contains(b.String(), e) {
+			t.Fatalf("unexpected explanation:\n\t(GOT): %s\n\t(WNT): contains %q", b.String(), e)
+		}
+	}
+
+	_, err = Resolve(t.Context(), Options{RepoDir: dir, Remote: "upstream"})
+	if !errors.Is(err, ErrUnknownProvider) {
+		t.Fatalf("unexpected error:\n\t(GOT): %v\n\t(WNT): %v", err, ErrUnknownProvider)
+	}
+	if hint := Hint(err); !strings.Contains(hint, "is disabled") {
+		t.Fatalf("unexpected hint:\n\t(GOT): %s\n\t(WNT): contains %q", hint, "is disabled")
+	}
+}
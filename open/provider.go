@@ -1,10 +1,14 @@
 package open
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"net"
 	"net/url"
-	"os"
+	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
 
@@ -12,83 +16,791 @@ import (
 )
 
 // defaultProviders is a list of built-in Providers
-var defaultProviders = []Provider{
+var defaultProviders = []PrefixProvider{
 	{
 		baseURL:      "https://github.com",
+		source:       "built-in",
 		commitPrefix: "commit",
 		pathPrefix:   "tree",
+		filePrefix:   "blob",
+		hostAliases:  []string{"ssh.github.com"},
 
 		rawLineFormat:   "L%l-L%l",
 		lineFormat:      "#L%d",
 		lineFormatRange: "#L%d-L%d",
+
+		headingStyle: githubHeadings,
+		plainQuery:   "plain=1",
+
+		blamePrefix:   "blame",
+		rawPrefix:     "raw",
+		historyPrefix: "commits",
+
+		comparePrefix:     "compare",
+		pullRequestPrefix: "pull/new",
 	},
 	{
 		baseURL:      "https://gitlab.com",
+		source:       "built-in",
 		commitPrefix: "-/commit",
 		pathPrefix:   "-/tree",
+		filePrefix:   "-/blob",
+		hostAliases:  []string{"altssh.gitlab.com"},
 
 		rawLineFormat:   "L%l-%l",
 		lineFormat:      "#L%d",
 		lineFormatRange: "#L%d-%d",
+
+		headingStyle: gitlabHeadings,
+		plainQuery:   "plain=1",
+
+		blamePrefix:   "-/blame",
+		rawPrefix:     "-/raw",
+		historyPrefix: "-/commits",
+
+		comparePrefix:     "-/compare",
+		pullRequestPrefix: "-/merge_requests/new",
+		pullRequestQuery:  "merge_request[source_branch]",
 	},
 	{
 		baseURL:      "https://bitbucket.org",
+		source:       "built-in",
 		commitPrefix: "commits",
 		pathPrefix:   "src",
+		hostAliases:  []string{"altssh.bitbucket.org"},
 
 		rawLineFormat:   "lines-%l:%l",
 		lineFormat:      "#lines-%d",
 		lineFormatRange: "#lines-%d:%d",
+
+		headingStyle: bitbucketHeadings,
+		plainQuery:   "fileviewer=file-view-default",
+
+		blamePrefix:   "annotate",
+		rawPrefix:     "raw",
+		historyPrefix: "history-node",
+
+		pullRequestPrefix: "pull-requests/new",
+		pullRequestQuery:  "source",
 	},
 	{
 		baseURL:      "https://codeberg.org",
+		source:       "built-in",
 		commitPrefix: "commit",
-		pathPrefix:   "tree",
+		pathPrefix:   "src/branch",
+
+		branchPathPrefix: "src/branch",
+		tagPathPrefix:    "src/tag",
+		commitPathPrefix: "src/commit",
 
 		rawLineFormat:   "L%l-L%l",
 		lineFormat:      "#L%d",
 		lineFormatRange: "#L%d-L%d",
+
+		headingStyle: githubHeadings,
+		plainQuery:   "display=source",
+
+		rawPrefix:     "raw",
+		historyPrefix: "commits",
+
+		comparePrefix: "compare",
+	},
+	{
+		baseURL:      "https://dev.azure.com",
+		source:       "built-in",
+		commitPrefix: "commit",
+		pathPrefix:   "GB",
+
+		branchPathPrefix: "GB",
+		tagPathPrefix:    "GT",
+		commitPathPrefix: "GC",
+
+		rawLineFormat:   "&line=%l&lineEnd=%l",
+		lineFormat:      "&line=%d",
+		lineFormatRange: "&line=%d&lineEnd=%d",
+
+		headingStyle: githubHeadings,
+		headingQuery: "anchor",
+
+		pathTemplate: "{base}/{repo}?path=/{path:query}&version={prefix}{ref:query}{anchor}",
 	},
 }
 
+// Providers returns the built-in Providers followed by the registered Providers and the Providers defined in git config
+//
+// Deprecated: use List.
 func Providers() []Provider {
-	return slices.Concat(defaultProviders, fromConfig())
+	return List()
+}
+
+// Provider builds the URLs of a Git platform, like GitHub, for the repositories it hosts.
+// A Provider may also implement Viewer, HeadingLinker, Comparer, PullRequester, RefKindLinker or HostAliaser.
+type Provider interface {
+	// BaseURL returns the base URL of the provider, whose host is matched against the host of remotes
+	BaseURL() string
+	// Source returns where the provider was defined, like `built-in` or the git config file
+	Source() string
+
+	// CommitURL returns the URL of a commit
+	CommitURL(repo, commitSHA string) string
+	// PathURL returns the URL of a file or directory at ref, with an anchor highlighting the lines when lstart is set
+	PathURL(repo, ref, path string, lstart, lend int) string
+	// RootURL returns the URL of the repository
+	RootURL(repo string) string
+}
+
+// Viewer is a Provider with views of paths other than the file and directory view, like Blame
+type Viewer interface {
+	// ViewURL returns the URL of a path in a view, or false when the view is not supported
+	ViewURL(view View, repo, ref, path string, lstart, lend int) (string, bool)
+}
+
+// FileLinker is a Provider that links to files apart from directories, like `blob` and `tree` on GitHub
+type FileLinker interface {
+	// FileURL returns the URL of a file with line anchors, where PathURL is used for directories
+	FileURL(repo, ref, path string, lstart, lend int) string
+}
+
+// HeadingLinker is a Provider that links to the headings of rendered Markdown files
+type HeadingLinker interface {
+	// HeadingStyle returns how anchors are derived from Markdown headings: `github`, `gitlab` or `bitbucket`
+	HeadingStyle() string
+	// HeadingURL returns the URL of a rendered file scrolled to a heading anchor
+	HeadingURL(repo, ref, path, anchor string) string
 }
 
-// Provider represents the Git platforms domain and URL pathing.
-type Provider struct {
-	baseURL      string
+// RefKindLinker is a Provider whose URLs depend on whether the ref is a branch, tag or commit
+type RefKindLinker interface {
+	// WithRefKind returns the Provider building URLs for refs of kind
+	WithRefKind(kind RefKind) Provider
+}
+
+// HostAliaser is a Provider whose remotes are also served on other hosts than the host of its base URL,
+// like `ssh.github.com`
+type HostAliaser interface {
+	// HostAliases returns the other hosts of remotes
+	HostAliases() []string
+}
+
+// Comparer is a Provider that compares refs
+type Comparer interface {
+	// CompareURL returns the URL comparing head to base, or false when comparing is not supported
+	CompareURL(repo, base, head string) (string, bool)
+}
+
+// PullRequester is a Provider with pull requests, or merge requests
+type PullRequester interface {
+	// PullRequestURL returns the URL creating a pull request from the head branch, or false when not supported
+	PullRequestURL(repo, head string) (string, bool)
+}
+
+// PrefixProvider is a Provider whose URLs join the base URL, repository, a prefix, ref and path,
+// like `https://github.com/<repo>/tree/<ref>/<path>`. It implements every optional Provider interface.
+type PrefixProvider struct {
+	baseURL string
+	// source describes where the provider was defined, like `built-in` or the git config file
+	source string
+	// hostAliases are the other hosts of remotes, like `ssh.github.com`
+	hostAliases []string
+	// hostPattern is the regular expression matching the hostnames of remotes, when the host of baseURL is a
+	// wildcard like `gitlab.*.corp` or a host regexp is set
+	hostPattern *regexp.Regexp
+	// localPaths are the absolute paths of local mirrors, like `/mnt/mirrors/github`, whose repositories open on
+	// the provider, each followed by `=` and the repository it maps to when set, like `/srv/git/tool.git=org/tool`
+	localPaths []string
+
 	commitPrefix string
 	pathPrefix   string
+	// filePrefix is the path prefix of files, when it differs from pathPrefix used for directories
+	filePrefix string
+	// branchPathPrefix, tagPathPrefix and commitPathPrefix are the path prefixes of files and directories at refs
+	// of each kind, like `src/branch` and `src/tag` on Gitea, used instead of pathPrefix and filePrefix when set
+	branchPathPrefix string
+	tagPathPrefix    string
+	commitPathPrefix string
 
 	rawLineFormat   string
 	lineFormat      string
 	lineFormatRange string
+
+	// headingStyle selects how anchors are derived from Markdown headings, defaulting to githubHeadings
+	headingStyle string
+	// plainQuery is the query string that shows the source of rendered files, so line anchors work
+	plainQuery string
+	// headingQuery is the query parameter of heading anchors, like `anchor` on Azure DevOps, when headings are not
+	// linked with a fragment
+	headingQuery string
+
+	// blamePrefix, rawPrefix and historyPrefix are the path prefixes of each View, empty when unsupported
+	blamePrefix   string
+	rawPrefix     string
+	historyPrefix string
+
+	// comparePrefix is the path prefix of comparisons, empty when unsupported
+	comparePrefix string
+	// pullRequestPrefix is the path prefix of new pull requests, empty when unsupported, followed by the head
+	// branch, or by pullRequestQuery set to the head branch when set
+	pullRequestPrefix string
+	pullRequestQuery  string
+
+	// commitTemplate, pathTemplate and rootTemplate are URL templates used instead of the prefixes when set
+	commitTemplate string
+	pathTemplate   string
+	rootTemplate   string
+
+	// refKind is the kind of the refs of path URLs, for templates
+	refKind RefKind
+}
+
+// presets maps each provider type to the base URL of the built-in Provider whose behaviour it inherits
+var presets = map[string]string{
+	"github":      "https://github.com",
+	"gitlab":      "https://gitlab.com",
+	"bitbucket":   "https://bitbucket.org",
+	"gitea":       "https://codeberg.org",
+	"forgejo":     "https://codeberg.org",
+	"azuredevops": "https://dev.azure.com",
+}
+
+// ProviderConfig describes a Provider, like the keys of an `[open "<url>"]` section in git config
+type ProviderConfig struct {
+	// BaseURL is the http or https URL of the provider, like `https://git.mydomain.dev`
+	BaseURL string
+
+	// Type inherits every field of a built-in provider, like `gitlab` for a self-hosted GitLab,
+	// that is not set: `github`, `gitlab`, `bitbucket`, `gitea`, `forgejo` or `azuredevops`. Host aliases are not
+	// inherited.
+	Type string
+
+	// HostAliases are the other hosts of remotes, like `ssh.github.com` or `git-ssh.mydomain.dev`
+	HostAliases []string
+
+	// HostRegexp is a regular expression matching the hostnames of remotes, like `^gitlab\.team-[a-z]+\.corp$`,
+	// replacing the host of BaseURL with the matched hostname. The host of BaseURL still matches when the regular
+	// expression does not. The host of BaseURL may also be a wildcard, where
+	// each `*` matches one label, like `gitlab.*.corp`.
+	HostRegexp string
+
+	// LocalPaths are the paths of local mirrors, like `/mnt/mirrors/github`, whose repositories open on the
+	// provider. Local remotes below one, like `/mnt/mirrors/github/org/repo.git`, open as `<BaseURL>/org/repo`.
+	// A path followed by `=` and a repository, like `/srv/git/tool.git=org/tool`, opens the local remote at the
+	// path as `<BaseURL>/org/tool`, and those below it within the repository, like `/srv/git/org=org` for
+	// `/srv/git/org/repo.git`.
+	LocalPaths []string
+
+	// CommitPrefix and PathPrefix are the path prefixes of commits and paths, like `commit` and `tree`
+	CommitPrefix string
+	PathPrefix   string
+	// FilePrefix is the path prefix of files, like `blob`, when it differs from PathPrefix used for directories
+	FilePrefix string
+	// BranchPathPrefix, TagPathPrefix and CommitPathPrefix are the path prefixes of files and directories at refs
+	// of each kind, like `src/branch`, `src/tag` and `src/commit` on Gitea and Forgejo. They are used instead of
+	// PathPrefix and FilePrefix when set.
+	BranchPathPrefix string
+	TagPathPrefix    string
+	CommitPathPrefix string
+
+	// LineFormat templates the line anchor, where up to two `%l` denote the start and end lines, like `L%l-L%l`.
+	// Line formats starting with `&` are query parameters instead, like `&line=%l&lineEnd=%l`, for path templates
+	// with a query. Line anchors are disabled when empty.
+	LineFormat string
+
+	// HeadingStyle selects how anchors are derived from Markdown headings: `github` (the default), `gitlab` or `bitbucket`
+	HeadingStyle string
+	// PlainQuery is the query string that shows the source of rendered files, like `plain=1`
+	PlainQuery string
+	// HeadingQuery is the query parameter of heading anchors, like `anchor`, when headings are not linked with a
+	// fragment like `#usage`
+	HeadingQuery string
+
+	// BlamePrefix, RawPrefix and HistoryPrefix are the path prefixes of each View, empty when unsupported
+	BlamePrefix   string
+	RawPrefix     string
+	HistoryPrefix string
+
+	// ComparePrefix is the path prefix of comparisons, like `compare`, empty when unsupported
+	ComparePrefix string
+	// PullRequestPrefix is the path prefix of new pull requests, like `pull/new`, empty when unsupported.
+	// It is followed by the head branch, or by PullRequestQuery set to the head branch, like `source`.
+	PullRequestPrefix string
+	PullRequestQuery  string
+
+	// CommitTemplate, PathTemplate and RootTemplate are URL templates used instead of the prefixes when set,
+	// like `{base}/{repo}/src/{ref}/{path}{anchor}`. Variables are escaped as path segments, unless
+	// suffixed with `:query` to escape them as a query value or `:raw` to insert them as is:
+	//
+	//	{base}      the base URL, never escaped
+	//	{repo}      the repository, like `owner/name`
+	//	{owner}     the owner, group or organization of the repository
+	//	{name}      the name of the repository
+	//	{commit}    the commit SHA, in CommitTemplate only
+	//	{ref}       the branch, tag or commit, in PathTemplate only
+	//	{refkind}   `branch`, `tag` or `commit`, in PathTemplate only
+	//	{prefix}    the path or file prefix of the ref kind, like `GB` or `GT`, in PathTemplate only
+	//	{path}      the path, in PathTemplate only
+	//	{anchor}    the line anchor of LineFormat, or heading anchor, with `#` or `&`, in PathTemplate only
+	CommitTemplate string
+	PathTemplate   string
+	RootTemplate   string
+}
+
+// NewProvider returns the Provider described by c, or an error if c is invalid
+func NewProvider(c ProviderConfig) (PrefixProvider, error) {
+	if err := validateBaseURL(c.BaseURL); err != nil {
+		return PrefixProvider{}, err
+	}
+	if c.Type != "" {
+		preset, err := presetConfig(c.Type)
+		if err != nil {
+			return PrefixProvider{}, err
+		}
+		preset.overlay(c)
+		c = preset
+	}
+	if c.CommitPrefix == "" && c.CommitTemplate == "" {
+		return PrefixProvider{}, errors.New("missing commit prefix")
+	}
+	if c.PathPrefix == "" && c.PathTemplate == "" {
+		return PrefixProvider{}, errors.New("missing path prefix")
+	}
+	if err := validateTemplates(c); err != nil {
+		return PrefixProvider{}, err
+	}
+	for _, alias := range c.HostAliases {
+		if err := validateHostAlias(alias); err != nil {
+			return PrefixProvider{}, &fieldError{key: "hostalias", value: alias, err: err}
+		}
+	}
+	hostPattern, err := parseHostPattern(c.BaseURL, c.HostRegexp)
+	if err != nil {
+		return PrefixProvider{}, err
+	}
+	var localPaths []string
+	for _, p := range c.LocalPaths {
+		if err := validateLocalPath(p); err != nil {
+			return PrefixProvider{}, &fieldError{key: "localpath", value: p, err: err}
+		}
+		path, repo := splitLocalPath(p)
+		if repo != "" {
+			path += "=" + repo
+		}
+		localPaths = append(localPaths, path)
+	}
+
+	lineFormat, lineFormatRange, err := parseRawLineFormat(c.LineFormat)
+	if err != nil {
+		return PrefixProvider{}, &fieldError{key: "lineformat", value: c.LineFormat, err: fmt.Errorf("invalid line format: %w", err)}
+	}
+	if strings.HasPrefix(c.LineFormat, "&") && c.PathTemplate == "" {
+		return PrefixProvider{}, &fieldError{key: "lineformat", value: c.LineFormat, err: errors.New("invalid line format: query parameters require a path template")}
+	}
+	if err := validateHeadingStyle(c.HeadingStyle); err != nil {
+		return PrefixProvider{}, &fieldError{key: "headingstyle", value: c.HeadingStyle, err: err}
+	}
+
+	return PrefixProvider{
+		baseURL:      strings.TrimSuffix(c.BaseURL, "/"),
+		hostAliases:  slices.Clone(c.HostAliases),
+		hostPattern:  hostPattern,
+		localPaths:   localPaths,
+		commitPrefix: c.CommitPrefix,
+		pathPrefix:   c.PathPrefix,
+		filePrefix:   c.FilePrefix,
+
+		branchPathPrefix: c.BranchPathPrefix,
+		tagPathPrefix:    c.TagPathPrefix,
+		commitPathPrefix: c.CommitPathPrefix,
+
+		rawLineFormat:   c.LineFormat,
+		lineFormat:      lineFormat,
+		lineFormatRange: lineFormatRange,
+
+		headingStyle: c.HeadingStyle,
+		plainQuery:   strings.TrimPrefix(c.PlainQuery, "?"),
+		headingQuery: c.HeadingQuery,
+
+		blamePrefix:   c.BlamePrefix,
+		rawPrefix:     c.RawPrefix,
+		historyPrefix: c.HistoryPrefix,
+
+		comparePrefix:     c.ComparePrefix,
+		pullRequestPrefix: c.PullRequestPrefix,
+		pullRequestQuery:  c.PullRequestQuery,
+
+		commitTemplate: c.CommitTemplate,
+		pathTemplate:   c.PathTemplate,
+		rootTemplate:   c.RootTemplate,
+	}, nil
+}
+
+// presetConfig returns the ProviderConfig of the built-in provider inherited by the provider type,
+// without the host aliases of the built-in provider
+func presetConfig(providerType string) (ProviderConfig, error) {
+	baseURL, ok := presets[providerType]
+	if !ok {
+		return ProviderConfig{}, fmt.Errorf("unknown provider type: %q, expected one of: %s",
+			providerType, strings.Join(slices.Sorted(maps.Keys(presets)), ", "))
+	}
+	p, _ := builtInProvider(baseURL)
+	c := p.config()
+	c.HostAliases = nil
+	return c, nil
+}
+
+// parseHostPattern returns the compiled regular expression matching the hostnames of remotes, the host regexp
+// when set or the wildcard host of baseURL, or nil when the host of baseURL is matched exactly
+func parseHostPattern(baseURL, hostRegexp string) (*regexp.Regexp, error) {
+	if hostRegexp != "" {
+		re, err := regexp.Compile(hostRegexp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host regexp: %w", err)
+		}
+		return re, nil
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil || !strings.Contains(u.Hostname(), "*") {
+		return nil, nil
+	}
+	labels := strings.Split(u.Hostname(), ".")
+	for i, label := range labels {
+		if label == "*" {
+			labels[i] = `[^.]+`
+			continue
+		}
+		if strings.Contains(label, "*") {
+			return nil, fmt.Errorf("invalid wildcard host: %q, `*` must be a whole label", u.Hostname())
+		}
+		labels[i] = regexp.QuoteMeta(label)
+	}
+	return regexp.MustCompile("(?i)^" + strings.Join(labels, `\.`) + "$"), nil
+}
+
+// validateHostAlias returns an error if alias is not a host, like `ssh.github.com`
+func validateHostAlias(alias string) error {
+	u, err := url.Parse("ssh://" + alias)
+	if err != nil || alias == "" || u.Host != alias {
+		return fmt.Errorf("invalid host alias: %q", alias)
+	}
+	return nil
+}
+
+// validateLocalPath returns an error if p is not an absolute path, like `/mnt/mirrors/github` or `~/mirrors`,
+// optionally followed by `=` and a repository, like `/srv/git/tool.git=org/tool`
+func validateLocalPath(p string) error {
+	path, repo := splitLocalPath(p)
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("invalid local path: %q, expected an absolute path", p)
+	}
+	if repo == "" && strings.Contains(p, "=") {
+		return fmt.Errorf("invalid local path: %q, expected a repository after `=`", p)
+	}
+	return nil
+}
+
+// splitLocalPath returns the cleaned absolute path of a local path, with the home directory expanded, and the
+// repository after its last `=`, like `org/tool` for `/srv/git/tool.git=org/tool`, or empty when not set
+func splitLocalPath(p string) (path, repo string) {
+	if i := strings.LastIndex(p, "="); i != -1 {
+		p, repo = p[:i], strings.Trim(p[i+1:], "/")
+	}
+	return filepath.Clean(expandHome(p)), repo
+}
+
+// validateTemplates returns an error if a URL template of c is set and invalid
+func validateTemplates(c ProviderConfig) error {
+	for _, t := range []struct {
+		key, tmpl string
+		allowed   []string
+	}{
+		{"commit", c.CommitTemplate, commitTemplateVars},
+		{"path", c.PathTemplate, pathTemplateVars},
+		{"root", c.RootTemplate, rootTemplateVars},
+	} {
+		if t.tmpl == "" {
+			continue
+		}
+		if err := validateTemplate(t.tmpl, t.allowed); err != nil {
+			return &fieldError{key: t.key + "template", value: t.tmpl, err: fmt.Errorf("invalid %s template: %w", t.key, err)}
+		}
+	}
+	return nil
+}
+
+// fieldError is an error of NewProvider with an invalid value of an optional field of ProviderConfig, which
+// loadConfig drops to keep the provider
+type fieldError struct {
+	// key is the git config key of the field, like `lineformat`
+	key   string
+	value string
+	err   error
+}
+
+// Error returns the message of the wrapped error
+func (e *fieldError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+// drop removes the invalid value of e from c, reporting false when c has no such value
+func (e *fieldError) drop(c *ProviderConfig) bool {
+	without := func(values []string) ([]string, bool) {
+		i := slices.Index(values, e.value)
+		if i == -1 {
+			return values, false
+		}
+		if values = slices.Delete(values, i, i+1); len(values) == 0 {
+			values = nil
+		}
+		return values, true
+	}
+
+	var ok bool
+	switch e.key {
+	case "hostalias":
+		c.HostAliases, ok = without(c.HostAliases)
+		return ok
+	case "localpath":
+		c.LocalPaths, ok = without(c.LocalPaths)
+		return ok
+	case "lineformat":
+		c.LineFormat = ""
+	case "headingstyle":
+		c.HeadingStyle = ""
+	case "committemplate":
+		c.CommitTemplate = ""
+	case "pathtemplate":
+		c.PathTemplate = ""
+	case "roottemplate":
+		c.RootTemplate = ""
+	default:
+		return false
+	}
+	return true
+}
+
+// validateBaseURL returns an error if rawURL is not an http or https URL with a host
+func validateBaseURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid base URL: %q", rawURL)
+	}
+	return nil
+}
+
+// validateHeadingStyle returns an error if style is not a supported heading style
+func validateHeadingStyle(style string) error {
+	switch style {
+	case "", githubHeadings, gitlabHeadings, bitbucketHeadings:
+		return nil
+	}
+	return fmt.Errorf("invalid heading style: %q, expected one of: %s, %s, %s", style, githubHeadings, gitlabHeadings, bitbucketHeadings)
 }
 
 // BaseURL returns the provider's base URL as a string
-func (p Provider) BaseURL() string {
+func (p PrefixProvider) BaseURL() string {
 	return p.baseURL
 }
 
+// Source returns where the provider was defined as a string, like `built-in` or the git config file
+func (p PrefixProvider) Source() string {
+	return p.source
+}
+
+// HostAliases returns the other hosts of remotes, like `ssh.github.com`
+func (p PrefixProvider) HostAliases() []string {
+	return slices.Clone(p.hostAliases)
+}
+
+// withHost returns the provider with the hostname of its base URL replaced, for providers with a host pattern
+func (p PrefixProvider) withHost(hostname string) PrefixProvider {
+	u, err := url.Parse(p.baseURL)
+	if err != nil {
+		return p
+	}
+	port := u.Port()
+	u.Host = hostname
+	if port != "" {
+		u.Host = net.JoinHostPort(hostname, port)
+	}
+	p.baseURL, p.hostPattern = u.String(), nil
+	return p
+}
+
 // CommitURL returns URL of a commit as a string
-func (p Provider) CommitURL(repo, commitSHA string) string {
-	return escapePath(strings.Join([]string{p.baseURL, repo, p.commitPrefix, commitSHA}, "/"))
+func (p PrefixProvider) CommitURL(repo, commitSHA string) string {
+	if p.commitTemplate != "" {
+		vars := repoVars(p.baseURL, repo)
+		vars[varCommit] = commitSHA
+		return expandTemplate(p.commitTemplate, vars)
+	}
+	return joinURL(p.baseURL, repo, p.commitPrefix, commitSHA)
+}
+
+// PathURL returns URL of a file or directory with line anchors as a string.
+// Rendered files like Markdown are shown as source when a line anchor is set.
+func (p PrefixProvider) PathURL(repo, ref, path string, lstart, lend int) string {
+	return p.pathURL(p.refKindPrefix(p.pathPrefix), repo, ref, path, lstart, lend)
 }
 
-// PathURL returns URL of a file with line anchors as a string
-func (p Provider) PathURL(repo, ref, path string, lstart, lend int) string {
-	u := strings.Join([]string{p.baseURL, repo, p.pathPrefix, ref, path}, "/")
-	return escapePath(u) + p.lineAnchor(lstart, lend)
+// FileURL returns URL of a file with line anchors as a string, with the file prefix when set.
+// Rendered files like Markdown are shown as source when a line anchor is set.
+func (p PrefixProvider) FileURL(repo, ref, path string, lstart, lend int) string {
+	return p.pathURL(p.refKindPrefix(p.fileOrPathPrefix()), repo, ref, path, lstart, lend)
+}
+
+// refKindPrefix returns the path prefix of the kind of the refs of the provider when set, or else prefix
+func (p PrefixProvider) refKindPrefix(prefix string) string {
+	var kindPrefix string
+	switch p.refKind {
+	case Branch:
+		kindPrefix = p.branchPathPrefix
+	case Tag:
+		kindPrefix = p.tagPathPrefix
+	case CommitRef:
+		kindPrefix = p.commitPathPrefix
+	}
+	if kindPrefix != "" {
+		return kindPrefix
+	}
+	return prefix
+}
+
+// fileOrPathPrefix returns the file prefix, or the path prefix when it is not set
+func (p PrefixProvider) fileOrPathPrefix() string {
+	if p.filePrefix != "" {
+		return p.filePrefix
+	}
+	return p.pathPrefix
+}
+
+// pathURL returns URL of a path below prefix with line anchors as a string
+func (p PrefixProvider) pathURL(prefix, repo, ref, path string, lstart, lend int) string {
+	if p.pathTemplate != "" {
+		return p.expandPathTemplate(prefix, repo, ref, path, p.lineAnchor(lstart, lend))
+	}
+	u := joinURL(p.baseURL, repo, prefix, ref, path)
+	if lstart > 0 && p.plainQuery != "" && isRendered(path) {
+		u += "?" + p.plainQuery
+	}
+	return u + p.lineAnchor(lstart, lend)
+}
+
+// HeadingStyle returns how anchors are derived from Markdown headings: `github`, `gitlab` or `bitbucket`
+func (p PrefixProvider) HeadingStyle() string {
+	if p.headingStyle == "" {
+		return githubHeadings
+	}
+	return p.headingStyle
+}
+
+// HeadingURL returns URL of a rendered file scrolled to a heading anchor as a string, with the anchor in the
+// heading query parameter when set, like `?anchor=usage`, or `&anchor=usage` after the query of a path template
+func (p PrefixProvider) HeadingURL(repo, ref, path, anchor string) string {
+	prefix := p.refKindPrefix(p.fileOrPathPrefix())
+	if p.headingQuery != "" {
+		query := url.Values{p.headingQuery: {anchor}}.Encode()
+		if p.pathTemplate != "" {
+			return p.expandPathTemplate(prefix, repo, ref, path, "&"+query)
+		}
+		return joinURL(p.baseURL, repo, prefix, ref, path) + "?" + query
+	}
+
+	anchor = "#" + (&url.URL{Fragment: anchor}).EscapedFragment()
+	if p.pathTemplate != "" {
+		return p.expandPathTemplate(prefix, repo, ref, path, anchor)
+	}
+	return joinURL(p.baseURL, repo, prefix, ref, path) + anchor
+}
+
+// expandPathTemplate returns URL of a path below prefix from the path template as a string, with the line or
+// heading anchor, which is empty when neither is set
+func (p PrefixProvider) expandPathTemplate(prefix, repo, ref, path, anchor string) string {
+	vars := repoVars(p.baseURL, repo)
+	vars[varRef], vars[varPath], vars[varAnchor] = ref, path, anchor
+	vars[varPrefix] = prefix
+	vars[varRefKind] = string(p.refKind)
+	if p.refKind == "" {
+		vars[varRefKind] = string(Branch)
+	}
+	return expandTemplate(p.pathTemplate, vars)
+}
+
+// WithRefKind returns the provider building path URLs for refs of kind, with its path prefix for the kind
+func (p PrefixProvider) WithRefKind(kind RefKind) Provider {
+	p.refKind = kind
+	return p
+}
+
+// ViewURL returns URL of a path in a view as a string, with line anchors in the Blame view, at the ref kind when
+// the path prefix of the ref kind ends with it, like `raw/tag/<ref>` for `src/tag` on Gitea.
+// It returns false when the provider does not support the view, or builds path URLs from a template.
+func (p PrefixProvider) ViewURL(view View, repo, ref, path string, lstart, lend int) (string, bool) {
+	var prefix string
+	switch view {
+	case Blame:
+		prefix = p.blamePrefix
+	case Raw:
+		prefix = p.rawPrefix
+	case History:
+		prefix = p.historyPrefix
+	}
+	if prefix == "" || p.pathTemplate != "" {
+		return "", false
+	}
+
+	kindPrefix := p.refKindPrefix(p.pathPrefix)
+	for _, kind := range []RefKind{Branch, Tag, CommitRef} {
+		if strings.HasSuffix(kindPrefix, "/"+string(kind)) {
+			prefix += "/" + string(kind)
+			break
+		}
+	}
+
+	u := joinURL(p.baseURL, repo, prefix, ref, path)
+	if view == Blame {
+		u += p.lineAnchor(lstart, lend)
+	}
+	return u, true
+}
+
+// CompareURL returns URL comparing head to base as a string.
+// It returns false when the provider does not support comparing.
+func (p PrefixProvider) CompareURL(repo, base, head string) (string, bool) {
+	if p.comparePrefix == "" {
+		return "", false
+	}
+	return joinURL(p.baseURL, repo, p.comparePrefix, base+"..."+head), true
+}
+
+// PullRequestURL returns URL creating a pull request from the head branch as a string.
+// It returns false when the provider does not support pull requests.
+func (p PrefixProvider) PullRequestURL(repo, head string) (string, bool) {
+	if p.pullRequestPrefix == "" {
+		return "", false
+	}
+	if p.pullRequestQuery == "" {
+		return joinURL(p.baseURL, repo, p.pullRequestPrefix, head), true
+	}
+	u := joinURL(p.baseURL, repo, p.pullRequestPrefix)
+	return u + "?" + url.Values{p.pullRequestQuery: {head}}.Encode(), true
 }
 
 // RootURL returns URL of the root repository as a string
-func (p Provider) RootURL(repo string) string {
-	return escapePath(strings.Join([]string{p.baseURL, repo}, "/"))
+func (p PrefixProvider) RootURL(repo string) string {
+	if p.rootTemplate != "" {
+		return expandTemplate(p.rootTemplate, repoVars(p.baseURL, repo))
+	}
+	return joinURL(p.baseURL, repo)
 }
 
-// lineAnchor returns a URL anchor highlighting a line or range of lines like `#L3` or `#L3-L10`
-func (p Provider) lineAnchor(start, end int) string {
+// lineAnchor returns a URL anchor highlighting a line or range of lines like `#L3` or `#L3-L10`, or the query
+// parameters of a line format starting with `&`, like `&line=3&lineEnd=10`
+func (p PrefixProvider) lineAnchor(start, end int) string {
 	if start == 0 || p.lineFormat == "" {
 		return ""
 	}
@@ -98,31 +810,93 @@ func (p Provider) lineAnchor(start, end int) string {
 	return fmt.Sprintf(p.lineFormatRange, start, end)
 }
 
-const getRegex = `^open\..*(prefix|format)$`
+const getRegex = `^open\..*(prefix|format|style|query|template|enabled|replace|type|hostalias|sshhost|hostregexp|localpath)$`
 
-// fromConfig returns a slice of [Provider] from the global Git config.
+// fromConfig returns a slice of [PrefixProvider] from the global Git config.
 //
 // The Git config structure includes a base URL as an argument, commit prefix, path prefix and line format string.
 //
 //	[open "https://git.mydomain.dev"]
 //	  commitprefix = commit
 //	  pathprefix = tree
+//	  fileprefix = blob
+//	  branchpathprefix = src/branch
+//	  tagpathprefix = src/tag
+//	  commitpathprefix = src/commit
 //	  lineformat = L%l-L%l
-func fromConfig() []Provider {
-	providers := []Provider{}
-	out := gitw.ConfigGetRegexp(getRegex)
+//	  headingstyle = github
+//	  plainquery = plain=1
+//	  headingquery = anchor
+//	  blameprefix = blame
+//	  rawprefix = raw
+//	  historyprefix = commits
+//	  compareprefix = compare
+//	  pullrequestprefix = pull/new
+//
+// URL templates may be set instead of, or to override, the commit, path and root prefixes:
+//
+//	[open "https://git.mydomain.dev"]
+//	  committemplate = {base}/{repo}/commit/{commit}
+//	  pathtemplate = {base}/{repo}?path={path:query}&version={ref:query}{anchor}
+//	  roottemplate = {base}/{owner}/_git/{name}
+//
+// A provider with the base URL of a built-in provider is merged over it, unless `replace` is true.
+// Any provider, including built-in providers, is disabled when `enabled` is false.
+//
+//	[open "https://github.com"]
+//	  pathprefix = blob
+//	[open "https://bitbucket.org"]
+//	  enabled = false
+//
+// A provider with a `type` inherits the keys that are not set from the built-in provider of that type.
+//
+//	[open "https://gitlab.mydomain.dev"]
+//	  type = gitlab
+//
+// `hostalias`, or `sshhost`, may be set many times to match remotes on other hosts than the base URL.
+//
+//	[open "https://git.mydomain.dev"]
+//	  hostalias = git-ssh.mydomain.dev
+//
+// The host of the base URL may be a wildcard, or `hostregexp` may be set, to match many hosts, replacing
+// the host of the base URL with the host of the remote.
+//
+//	[open "https://gitlab.*.corp"]
+//	  type = gitlab
+//
+// `localpath` may be set many times to open local remotes below it, like mirrors, on the provider. A path followed
+// by `=` and a repository opens the local remote at the path, and those below it, on the repository.
+//
+//	[open "https://github.com"]
+//	  localpath = /mnt/mirrors/github
+//	  localpath = /srv/git/tool.git=org/tool
+func fromConfig() []PrefixProvider {
+	providers, _, _ := loadConfig(context.Background(), "")
+	return providers
+}
+
+// loadConfig returns a slice of [PrefixProvider] from the Git config of the repository at path, like fromConfig,
+// the base URLs of the providers skipped because they are invalid, and of the providers disabled
+func loadConfig(ctx context.Context, path string) (providers []PrefixProvider, invalid []string, disabled []string) {
+	providers = []PrefixProvider{}
+	out := gitw.ConfigGetRegexpOrigin(ctx, path, getRegex)
 	if len(out) == 0 {
-		return providers
+		return providers, nil, nil
 	}
 
 	var order []string
-	urls := make(map[string]*Provider)
+	configs := make(map[string]*ProviderConfig)
+	origins := make(map[string][]string)
+	enabled := make(map[string]bool)
+	replace := make(map[string]bool)
 	for line := range strings.SplitSeq(out, "\n") {
-		s := strings.SplitN(line, " ", 2)
-		if len(s) != 2 {
-			continue
+		origin, line, ok := strings.Cut(line, "\t")
+		if !ok {
+			origin, line = "", origin
 		}
-		fullKey, value := s[0], s[1]
+
+		// Keys without a value, like booleans set to true, have no space
+		fullKey, value, _ := strings.Cut(line, " ")
 
 		rest, ok := strings.CutPrefix(fullKey, "open.")
 		if !ok {
@@ -135,64 +909,228 @@ func fromConfig() []Provider {
 		}
 
 		rawURL, key := rest[:i], rest[i+1:]
+		if b, ok := builtInProvider(rawURL); ok {
+			rawURL = b.baseURL
+		}
 
-		entry := urls[rawURL]
+		entry := configs[rawURL]
 		if entry == nil {
-			entry = &Provider{}
-			urls[rawURL] = entry
+			entry = &ProviderConfig{BaseURL: rawURL}
+			configs[rawURL] = entry
 			order = append(order, rawURL)
 		}
+		if origin != "" && !slices.Contains(origins[rawURL], origin) {
+			origins[rawURL] = append(origins[rawURL], origin)
+		}
 
 		switch key {
+		case "type":
+			entry.Type = value
+		case "hostregexp":
+			entry.HostRegexp = value
+		case "hostalias", "sshhost":
+			entry.HostAliases = append(entry.HostAliases, value)
+		case "localpath":
+			entry.LocalPaths = append(entry.LocalPaths, value)
 		case "commitprefix":
-			entry.commitPrefix = value
+			entry.CommitPrefix = value
 		case "pathprefix":
-			entry.pathPrefix = value
+			entry.PathPrefix = value
+		case "fileprefix":
+			entry.FilePrefix = value
+		case "branchpathprefix":
+			entry.BranchPathPrefix = value
+		case "tagpathprefix":
+			entry.TagPathPrefix = value
+		case "commitpathprefix":
+			entry.CommitPathPrefix = value
 		case "lineformat":
-			entry.rawLineFormat = value
+			entry.LineFormat = value
+		case "headingstyle":
+			entry.HeadingStyle = value
+		case "plainquery":
+			entry.PlainQuery = value
+		case "headingquery":
+			entry.HeadingQuery = value
+		case "blameprefix":
+			entry.BlamePrefix = value
+		case "rawprefix":
+			entry.RawPrefix = value
+		case "historyprefix":
+			entry.HistoryPrefix = value
+		case "compareprefix":
+			entry.ComparePrefix = value
+		case "pullrequestprefix":
+			entry.PullRequestPrefix = value
+		case "pullrequestquery":
+			entry.PullRequestQuery = value
+		case "committemplate":
+			entry.CommitTemplate = value
+		case "pathtemplate":
+			entry.PathTemplate = value
+		case "roottemplate":
+			entry.RootTemplate = value
+		case "enabled", "replace":
+			b, err := parseConfigBool(value)
+			if err != nil {
+				warnf("invalid %s for %q in git config: %q, expected true or false", key, rawURL, value)
+				continue
+			}
+			if key == "enabled" {
+				enabled[rawURL] = b
+			} else {
+				replace[rawURL] = b
+			}
 		}
 	}
 
 	for _, k := range order {
-		var skip bool
-
-		u, err := url.Parse(k)
-		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
-			fmt.Fprintf(os.Stderr, "warning: invalid provider URL in git config: %q, skipping provider\n", k)
-			skip = true
+		if e, ok := enabled[k]; ok && !e {
+			disabled = append(disabled, k)
+			continue
 		}
 
-		v := urls[k]
-		if v.commitPrefix == "" {
-			fmt.Fprintf(os.Stderr, "warning: provider %q is missing commitprefix in git config, skipping provider\n", k)
-			skip = true
+		// Providers with the base URL of a built-in provider are merged over it
+		b, isBuiltIn := builtInProvider(k)
+		if isBuiltIn && !replace[k] {
+			merged := b.config()
+			merged.overlay(*configs[k])
+			configs[k] = &merged
 		}
-		if v.pathPrefix == "" {
-			fmt.Fprintf(os.Stderr, "warning: provider %q is missing pathprefix in git config, skipping provider\n", k)
-			skip = true
+
+		// Invalid values of optional keys are dropped, keeping the provider
+		c := configs[k]
+		p, err := NewProvider(*c)
+		var fe *fieldError
+		for errors.As(err, &fe) && fe.drop(c) {
+			warnf("invalid %s for %q in git config: %v, ignoring it", fe.key, k, err)
+			p, err = NewProvider(*c)
 		}
-		if skip {
+		if err != nil {
+			warnf("invalid provider %q in git config: %v, skipping provider", k, err)
+			invalid = append(invalid, k)
 			continue
 		}
+		if c.LineFormat == "" && c.Type == "" {
+			warnf("provider %q is missing lineformat in git config", k)
+		}
 
-		lineFormat, lineFormatRange, err := parseRawLineFormat(v.rawLineFormat)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "warning: invalid lineformat for %q in git config: %v\n", k, err)
-		} else if lineFormat == "" {
-			fmt.Fprintf(os.Stderr, "warning: provider %q is missing lineformat in git config\n", k)
+		p.source = "git config"
+		if len(origins[k]) > 0 {
+			p.source += " (" + strings.Join(origins[k], ", ") + ")"
 		}
+		switch {
+		case isBuiltIn && replace[k]:
+			p.source += ", replacing built-in"
+		case isBuiltIn:
+			p.source += ", over built-in"
+		}
+		providers = append(providers, p)
+	}
 
-		v.baseURL = k
-		v.lineFormat = lineFormat
-		v.lineFormatRange = lineFormatRange
-		providers = append(providers, *v)
+	return providers, invalid, disabled
+}
+
+// builtInProvider returns the built-in Provider with the base URL, ignoring a trailing `/`
+func builtInProvider(baseURL string) (PrefixProvider, bool) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	i := slices.IndexFunc(defaultProviders, func(p PrefixProvider) bool { return p.baseURL == baseURL })
+	if i == -1 {
+		return PrefixProvider{}, false
 	}
+	return defaultProviders[i], true
+}
 
-	return providers
+// config returns the ProviderConfig describing the provider
+func (p PrefixProvider) config() ProviderConfig {
+	c := ProviderConfig{
+		BaseURL:           p.baseURL,
+		HostAliases:       slices.Clone(p.hostAliases),
+		LocalPaths:        slices.Clone(p.localPaths),
+		CommitPrefix:      p.commitPrefix,
+		PathPrefix:        p.pathPrefix,
+		FilePrefix:        p.filePrefix,
+		BranchPathPrefix:  p.branchPathPrefix,
+		TagPathPrefix:     p.tagPathPrefix,
+		CommitPathPrefix:  p.commitPathPrefix,
+		LineFormat:        p.rawLineFormat,
+		HeadingStyle:      p.headingStyle,
+		PlainQuery:        p.plainQuery,
+		HeadingQuery:      p.headingQuery,
+		BlamePrefix:       p.blamePrefix,
+		RawPrefix:         p.rawPrefix,
+		HistoryPrefix:     p.historyPrefix,
+		ComparePrefix:     p.comparePrefix,
+		PullRequestPrefix: p.pullRequestPrefix,
+		PullRequestQuery:  p.pullRequestQuery,
+		CommitTemplate:    p.commitTemplate,
+		PathTemplate:      p.pathTemplate,
+		RootTemplate:      p.rootTemplate,
+	}
+	if p.hostPattern != nil {
+		c.HostRegexp = p.hostPattern.String()
+	}
+	return c
+}
+
+// overlay sets the fields of c to the fields set in o, adding the host aliases and local paths of o
+func (c *ProviderConfig) overlay(o ProviderConfig) {
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&c.BaseURL, o.BaseURL},
+		{&c.Type, o.Type},
+		{&c.HostRegexp, o.HostRegexp},
+		{&c.CommitPrefix, o.CommitPrefix},
+		{&c.PathPrefix, o.PathPrefix},
+		{&c.FilePrefix, o.FilePrefix},
+		{&c.BranchPathPrefix, o.BranchPathPrefix},
+		{&c.TagPathPrefix, o.TagPathPrefix},
+		{&c.CommitPathPrefix, o.CommitPathPrefix},
+		{&c.LineFormat, o.LineFormat},
+		{&c.HeadingStyle, o.HeadingStyle},
+		{&c.PlainQuery, o.PlainQuery},
+		{&c.HeadingQuery, o.HeadingQuery},
+		{&c.BlamePrefix, o.BlamePrefix},
+		{&c.RawPrefix, o.RawPrefix},
+		{&c.HistoryPrefix, o.HistoryPrefix},
+		{&c.ComparePrefix, o.ComparePrefix},
+		{&c.PullRequestPrefix, o.PullRequestPrefix},
+		{&c.PullRequestQuery, o.PullRequestQuery},
+		{&c.CommitTemplate, o.CommitTemplate},
+		{&c.PathTemplate, o.PathTemplate},
+		{&c.RootTemplate, o.RootTemplate},
+	} {
+		if f.src != "" {
+			*f.dst = f.src
+		}
+	}
+	for _, alias := range o.HostAliases {
+		if !slices.Contains(c.HostAliases, alias) {
+			c.HostAliases = append(c.HostAliases, alias)
+		}
+	}
+	for _, p := range o.LocalPaths {
+		if !slices.Contains(c.LocalPaths, p) {
+			c.LocalPaths = append(c.LocalPaths, p)
+		}
+	}
+}
+
+// parseConfigBool parses a git config boolean, where an empty value is true
+func parseConfigBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "", "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean: %q", value)
 }
 
 // parseRawLineFormat parses the single argument line format and range from the raw line format
-// with an html anchor or returns a validation error
+// with an html anchor, or query parameters when it starts with `&`, or returns a validation error
 func parseRawLineFormat(rawLineFormat string) (lineFormat, lineFormatRange string, err error) {
 	count, err := validateLineFormat(rawLineFormat)
 	if err != nil {
@@ -201,7 +1139,7 @@ func parseRawLineFormat(rawLineFormat string) (lineFormat, lineFormatRange strin
 	if count == 0 {
 		return "", "", nil
 	}
-	if !strings.HasPrefix(rawLineFormat, "#") {
+	if !strings.HasPrefix(rawLineFormat, "#") && !strings.HasPrefix(rawLineFormat, "&") {
 		rawLineFormat = "#" + rawLineFormat
 	}
 
@@ -238,7 +1176,26 @@ func ltod(s string) string {
 	return strings.ReplaceAll(s, "%l", "%d")
 }
 
-// escapePath escapes the URL with url.PathEscape, but unescapes `/` and trims trailing `/`
-func escapePath(u string) string {
-	return strings.TrimSuffix(strings.ReplaceAll(url.PathEscape(u), "%2F", "/"), "/")
+// joinURL returns baseURL with the `/` separated segments of each part appended to its path, like the repository,
+// prefix, ref and path. Each segment is escaped on its own with url.PathEscape, so `#`, `%`, `?`, spaces and
+// unicode in refs and paths are never read as another part of the URL. Empty segments, like trailing `/`, are
+// dropped.
+func joinURL(baseURL string, parts ...string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL
+	}
+
+	path, rawPath := strings.TrimSuffix(u.Path, "/"), strings.TrimSuffix(u.EscapedPath(), "/")
+	for _, part := range parts {
+		for segment := range strings.SplitSeq(part, "/") {
+			if segment == "" {
+				continue
+			}
+			path += "/" + segment
+			rawPath += "/" + url.PathEscape(segment)
+		}
+	}
+	u.Path, u.RawPath = path, rawPath
+	return u.String()
 }
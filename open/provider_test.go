@@ -3,8 +3,8 @@ package open
 import (
 	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 	"testing"
@@ -17,6 +17,14 @@ import (
 
 const repo = "arbourd/git-open"
 
+// compareRegexps compares the host patterns of providers by their source
+var compareRegexps = cmp.Comparer(func(a, b *regexp.Regexp) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.String() == b.String()
+})
+
 func TestDefaultProviders(t *testing.T) {
 	for _, p := range defaultProviders {
 		u, err := url.Parse(p.BaseURL())
@@ -50,9 +58,163 @@ func TestDefaultProvidersLineFormat(t *testing.T) {
 	}
 }
 
+func TestNewProvider(t *testing.T) {
+	mirrors := t.TempDir()
+
+	cases := map[string]struct {
+		config           ProviderConfig
+		expectedProvider PrefixProvider
+		wantErr          bool
+	}{
+		"minimal": {
+			config:           ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree"},
+			expectedProvider: PrefixProvider{baseURL: "https://git.example.dev", commitPrefix: "commit", pathPrefix: "tree"},
+		},
+		"every field": {
+			config: ProviderConfig{
+				BaseURL:       "https://git.example.dev",
+				CommitPrefix:  "-/commit",
+				PathPrefix:    "-/tree",
+				LineFormat:    "L%l-%l",
+				HeadingStyle:  "gitlab",
+				PlainQuery:    "?plain=1",
+				HeadingQuery:  "anchor",
+				BlamePrefix:   "-/blame",
+				RawPrefix:     "-/raw",
+				HistoryPrefix: "-/commits",
+			},
+			expectedProvider: PrefixProvider{
+				baseURL:         "https://git.example.dev",
+				commitPrefix:    "-/commit",
+				pathPrefix:      "-/tree",
+				rawLineFormat:   "L%l-%l",
+				lineFormat:      "#L%d",
+				lineFormatRange: "#L%d-%d",
+				headingStyle:    "gitlab",
+				plainQuery:      "plain=1",
+				headingQuery:    "anchor",
+				blamePrefix:     "-/blame",
+				rawPrefix:       "-/raw",
+				historyPrefix:   "-/commits",
+			},
+		},
+		"templates instead of prefixes": {
+			config: ProviderConfig{
+				BaseURL:        "https://git.example.dev",
+				CommitTemplate: "{base}/{repo}/commit/{commit}",
+				PathTemplate:   "{base}/{repo}/src/{refkind}/{ref}/{path}{anchor}",
+				RootTemplate:   "{base}/{owner}/_git/{name}",
+			},
+			expectedProvider: PrefixProvider{
+				baseURL:        "https://git.example.dev",
+				commitTemplate: "{base}/{repo}/commit/{commit}",
+				pathTemplate:   "{base}/{repo}/src/{refkind}/{ref}/{path}{anchor}",
+				rootTemplate:   "{base}/{owner}/_git/{name}",
+			},
+		},
+		"type": {
+			config: ProviderConfig{BaseURL: "https://git.example.dev", Type: "github", PathPrefix: "blob"},
+			expectedProvider: func() PrefixProvider {
+				p := defaultProviders[0]
+				p.baseURL, p.source, p.pathPrefix, p.hostAliases = "https://git.example.dev", "", "blob", nil
+				return p
+			}(),
+		},
+		"unknown type": {
+			config:  ProviderConfig{BaseURL: "https://git.example.dev", Type: "sourcehut"},
+			wantErr: true,
+		},
+		"host aliases": {
+			config:           ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree", HostAliases: []string{"git-ssh.example.dev"}},
+			expectedProvider: PrefixProvider{baseURL: "https://git.example.dev", commitPrefix: "commit", pathPrefix: "tree", hostAliases: []string{"git-ssh.example.dev"}},
+		},
+		"invalid host alias": {
+			config:  ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree", HostAliases: []string{"git-ssh.example.dev/path"}},
+			wantErr: true,
+		},
+		"wildcard host": {
+			config:           ProviderConfig{BaseURL: "https://git.*.example.dev", CommitPrefix: "commit", PathPrefix: "tree"},
+			expectedProvider: PrefixProvider{baseURL: "https://git.*.example.dev", commitPrefix: "commit", pathPrefix: "tree", hostPattern: regexp.MustCompile(`(?i)^git\.[^.]+\.example\.dev$`)},
+		},
+		"host regexp": {
+			config:           ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree", HostRegexp: `^git[0-9]+\.example\.dev$`},
+			expectedProvider: PrefixProvider{baseURL: "https://git.example.dev", commitPrefix: "commit", pathPrefix: "tree", hostPattern: regexp.MustCompile(`^git[0-9]+\.example\.dev$`)},
+		},
+		"local paths": {
+			config:           ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree", LocalPaths: []string{filepath.Join(mirrors, "git") + string(filepath.Separator)}},
+			expectedProvider: PrefixProvider{baseURL: "https://git.example.dev", commitPrefix: "commit", pathPrefix: "tree", localPaths: []string{filepath.Join(mirrors, "git")}},
+		},
+		"local path with a repository": {
+			config:           ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree", LocalPaths: []string{filepath.Join(mirrors, "tool.git") + "=/org/tool/"}},
+			expectedProvider: PrefixProvider{baseURL: "https://git.example.dev", commitPrefix: "commit", pathPrefix: "tree", localPaths: []string{filepath.Join(mirrors, "tool.git") + "=org/tool"}},
+		},
+		"local path without a repository after =": {
+			config:  ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree", LocalPaths: []string{filepath.Join(mirrors, "tool.git") + "="}},
+			wantErr: true,
+		},
+		"relative local path": {
+			config:  ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree", LocalPaths: []string{"mirrors/git"}},
+			wantErr: true,
+		},
+		"partial wildcard label": {
+			config:  ProviderConfig{BaseURL: "https://git-*.example.dev", CommitPrefix: "commit", PathPrefix: "tree"},
+			wantErr: true,
+		},
+		"invalid host regexp": {
+			config:  ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree", HostRegexp: `^git(`},
+			wantErr: true,
+		},
+		"invalid template": {
+			config:  ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree", RootTemplate: "{base}/{path}"},
+			wantErr: true,
+		},
+		"invalid base URL": {
+			config:  ProviderConfig{BaseURL: "git.example.dev", CommitPrefix: "commit", PathPrefix: "tree"},
+			wantErr: true,
+		},
+		"non-web scheme": {
+			config:  ProviderConfig{BaseURL: "ssh://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree"},
+			wantErr: true,
+		},
+		"missing commit prefix": {
+			config:  ProviderConfig{BaseURL: "https://git.example.dev", PathPrefix: "tree"},
+			wantErr: true,
+		},
+		"missing path prefix": {
+			config:  ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit"},
+			wantErr: true,
+		},
+		"invalid line format": {
+			config:  ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree", LineFormat: "L%l-%l-%l"},
+			wantErr: true,
+		},
+		"invalid heading style": {
+			config:  ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree", HeadingStyle: "sourcehut"},
+			wantErr: true,
+		},
+		"query line format without a path template": {
+			config:  ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree", LineFormat: "&line=%l"},
+			wantErr: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := NewProvider(c.config)
+			if err != nil && !c.wantErr {
+				t.Fatalf("unexpected error:\n\t(GOT): %#v\n\t(WNT): nil", err)
+			} else if err == nil && c.wantErr {
+				t.Fatalf("expected error:\n\t(GOT): nil")
+			} else if !cmp.Equal(p, c.expectedProvider, cmp.AllowUnexported(PrefixProvider{}), compareRegexps) {
+				t.Fatalf("unexpected provider:\n\t(GOT): %#v\n\t(WNT): %#v", p, c.expectedProvider)
+			}
+		})
+	}
+}
+
 func TestCommitURL(t *testing.T) {
 	cases := map[string]struct {
-		p           Provider
+		p           PrefixProvider
 		commit      string
 		expectedURL string
 	}{
@@ -76,6 +238,11 @@ func TestCommitURL(t *testing.T) {
 			commit:      "7605d91",
 			expectedURL: "https://codeberg.org/arbourd/git-open/commit/7605d91",
 		},
+		"azure devops": {
+			p:           defaultProviders[4],
+			commit:      "7605d91",
+			expectedURL: "https://dev.azure.com/arbourd/git-open/commit/7605d91",
+		},
 	}
 
 	for name, c := range cases {
@@ -90,7 +257,7 @@ func TestCommitURL(t *testing.T) {
 
 func TestPathURL(t *testing.T) {
 	cases := map[string]struct {
-		p           Provider
+		p           PrefixProvider
 		ref         string
 		path        string
 		lstart      int
@@ -134,7 +301,106 @@ func TestPathURL(t *testing.T) {
 			p:           defaultProviders[3],
 			ref:         "main",
 			path:        "main.go",
-			expectedURL: "https://codeberg.org/arbourd/git-open/tree/main/main.go",
+			expectedURL: "https://codeberg.org/arbourd/git-open/src/branch/main/main.go",
+		},
+		"codeberg tag": {
+			p:           defaultProviders[3].WithRefKind(Tag).(PrefixProvider),
+			ref:         "v1.0.0",
+			path:        "main.go",
+			expectedURL: "https://codeberg.org/arbourd/git-open/src/tag/v1.0.0/main.go",
+		},
+		"codeberg commit": {
+			p:           defaultProviders[3].WithRefKind(CommitRef).(PrefixProvider),
+			ref:         "7605d91",
+			path:        "main.go",
+			expectedURL: "https://codeberg.org/arbourd/git-open/src/commit/7605d91/main.go",
+		},
+		"codeberg branch": {
+			p:           defaultProviders[3].WithRefKind(Branch).(PrefixProvider),
+			ref:         "main",
+			path:        "docs",
+			expectedURL: "https://codeberg.org/arbourd/git-open/src/branch/main/docs",
+		},
+		"azure devops": {
+			p:           defaultProviders[4],
+			ref:         "main",
+			path:        "main.go",
+			expectedURL: "https://dev.azure.com/arbourd/git-open?path=/main.go&version=GBmain",
+		},
+		"azure devops with line anchor range": {
+			p:           defaultProviders[4],
+			ref:         "main",
+			path:        "cmd/main.go",
+			lstart:      10,
+			lend:        20,
+			expectedURL: "https://dev.azure.com/arbourd/git-open?path=/cmd%2Fmain.go&version=GBmain&line=10&lineEnd=20",
+		},
+		"azure devops tag": {
+			p:           defaultProviders[4].WithRefKind(Tag).(PrefixProvider),
+			ref:         "v1.0.0",
+			path:        "main.go",
+			lstart:      3,
+			expectedURL: "https://dev.azure.com/arbourd/git-open?path=/main.go&version=GTv1.0.0&line=3",
+		},
+		"azure devops commit": {
+			p:           defaultProviders[4].WithRefKind(CommitRef).(PrefixProvider),
+			ref:         "7605d91",
+			path:        "main.go",
+			expectedURL: "https://dev.azure.com/arbourd/git-open?path=/main.go&version=GC7605d91",
+		},
+		"azure devops branch with a slash": {
+			p:           defaultProviders[4].WithRefKind(Branch).(PrefixProvider),
+			ref:         "feature/a&b",
+			path:        "docs",
+			expectedURL: "https://dev.azure.com/arbourd/git-open?path=/docs&version=GBfeature%2Fa%26b",
+		},
+		"prefix of another ref kind is not used": {
+			p:           PrefixProvider{baseURL: "https://git.example.dev", pathPrefix: "tree", tagPathPrefix: "src/tag"},
+			ref:         "main",
+			path:        "main.go",
+			expectedURL: "https://git.example.dev/arbourd/git-open/tree/main/main.go",
+		},
+		"github rendered file": {
+			p:           defaultProviders[0],
+			ref:         "main",
+			path:        "README.md",
+			expectedURL: "https://github.com/arbourd/git-open/tree/main/README.md",
+		},
+		"github rendered file with line anchor": {
+			p:           defaultProviders[0],
+			ref:         "main",
+			path:        "README.md",
+			lstart:      3,
+			expectedURL: "https://github.com/arbourd/git-open/tree/main/README.md?plain=1#L3",
+		},
+		"gitlab rendered file with line anchor": {
+			p:           defaultProviders[1],
+			ref:         "main",
+			path:        "docs/index.rst",
+			lstart:      3,
+			lend:        5,
+			expectedURL: "https://gitlab.com/arbourd/git-open/-/tree/main/docs/index.rst?plain=1#L3-5",
+		},
+		"bitbucket rendered file with line anchor": {
+			p:           defaultProviders[2],
+			ref:         "main",
+			path:        "README.md",
+			lstart:      3,
+			expectedURL: "https://bitbucket.org/arbourd/git-open/src/main/README.md?fileviewer=file-view-default#lines-3",
+		},
+		"codeberg rendered file with line anchor": {
+			p:           defaultProviders[3],
+			ref:         "main",
+			path:        "README.MD",
+			lstart:      3,
+			expectedURL: "https://codeberg.org/arbourd/git-open/src/branch/main/README.MD?display=source#L3",
+		},
+		"rendered file without a plain query": {
+			p:           PrefixProvider{baseURL: "https://git.example.dev", pathPrefix: "tree", lineFormat: "#L%d"},
+			ref:         "main",
+			path:        "README.md",
+			lstart:      3,
+			expectedURL: "https://git.example.dev/arbourd/git-open/tree/main/README.md#L3",
 		},
 	}
 
@@ -148,9 +414,259 @@ func TestPathURL(t *testing.T) {
 	}
 }
 
+func TestFileURL(t *testing.T) {
+	cases := map[string]struct {
+		p           PrefixProvider
+		lstart      int
+		expectedURL string
+	}{
+		"github": {
+			p:           defaultProviders[0],
+			expectedURL: "https://github.com/arbourd/git-open/blob/main/main.go",
+		},
+		"github with line anchor": {
+			p:           defaultProviders[0],
+			lstart:      3,
+			expectedURL: "https://github.com/arbourd/git-open/blob/main/main.go#L3",
+		},
+		"gitlab": {
+			p:           defaultProviders[1],
+			expectedURL: "https://gitlab.com/arbourd/git-open/-/blob/main/main.go",
+		},
+		"bitbucket": {
+			p:           defaultProviders[2],
+			expectedURL: "https://bitbucket.org/arbourd/git-open/src/main/main.go",
+		},
+		"without a file prefix": {
+			p:           PrefixProvider{baseURL: "https://git.example.dev", pathPrefix: "tree"},
+			expectedURL: "https://git.example.dev/arbourd/git-open/tree/main/main.go",
+		},
+		"file prefix": {
+			p:           PrefixProvider{baseURL: "https://git.example.dev", pathPrefix: "tree", filePrefix: "blob"},
+			expectedURL: "https://git.example.dev/arbourd/git-open/blob/main/main.go",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			url := c.p.FileURL(repo, "main", "main.go", c.lstart, 0)
+			if url != c.expectedURL {
+				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", url, c.expectedURL)
+			}
+		})
+	}
+}
+
+func TestHeadingURL(t *testing.T) {
+	cases := map[string]struct {
+		p           PrefixProvider
+		anchor      string
+		expectedURL string
+	}{
+		"github": {
+			p:           defaultProviders[0],
+			anchor:      "providers",
+			expectedURL: "https://github.com/arbourd/git-open/blob/main/README.md#providers",
+		},
+		"bitbucket": {
+			p:           defaultProviders[2],
+			anchor:      "markdown-header-providers",
+			expectedURL: "https://bitbucket.org/arbourd/git-open/src/main/README.md#markdown-header-providers",
+		},
+		"unicode anchor": {
+			p:           defaultProviders[0],
+			anchor:      "café",
+			expectedURL: "https://github.com/arbourd/git-open/blob/main/README.md#caf%C3%A9",
+		},
+		"azure devops": {
+			p:           defaultProviders[4],
+			anchor:      "providers",
+			expectedURL: "https://dev.azure.com/arbourd/git-open?path=/README.md&version=GBmain&anchor=providers",
+		},
+		"heading query": {
+			p:           PrefixProvider{baseURL: "https://git.example.dev", commitPrefix: "commit", pathPrefix: "tree", headingQuery: "anchor"},
+			anchor:      "café",
+			expectedURL: "https://git.example.dev/arbourd/git-open/tree/main/README.md?anchor=caf%C3%A9",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			url := c.p.HeadingURL(repo, "main", "README.md", c.anchor)
+			if url != c.expectedURL {
+				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", url, c.expectedURL)
+			}
+		})
+	}
+}
+
+func TestViewURL(t *testing.T) {
+	cases := map[string]struct {
+		p           PrefixProvider
+		view        View
+		path        string
+		lstart      int
+		lend        int
+		expectedURL string
+		unsupported bool
+	}{
+		"github blame with lines": {
+			p:           defaultProviders[0],
+			view:        Blame,
+			path:        "main.go",
+			lstart:      3,
+			lend:        10,
+			expectedURL: "https://github.com/arbourd/git-open/blame/main/main.go#L3-L10",
+		},
+		"github raw drops lines": {
+			p:           defaultProviders[0],
+			view:        Raw,
+			path:        "main.go",
+			lstart:      3,
+			expectedURL: "https://github.com/arbourd/git-open/raw/main/main.go",
+		},
+		"github history of the root": {
+			p:           defaultProviders[0],
+			view:        History,
+			path:        "",
+			expectedURL: "https://github.com/arbourd/git-open/commits/main",
+		},
+		"gitlab blame": {
+			p:           defaultProviders[1],
+			view:        Blame,
+			path:        "main.go",
+			lstart:      3,
+			expectedURL: "https://gitlab.com/arbourd/git-open/-/blame/main/main.go#L3",
+		},
+		"bitbucket history": {
+			p:           defaultProviders[2],
+			view:        History,
+			path:        "open",
+			expectedURL: "https://bitbucket.org/arbourd/git-open/history-node/main/open",
+		},
+		"codeberg raw": {
+			p:           defaultProviders[3],
+			view:        Raw,
+			path:        "main.go",
+			expectedURL: "https://codeberg.org/arbourd/git-open/raw/branch/main/main.go",
+		},
+		"codeberg raw at a tag": {
+			p:           defaultProviders[3].WithRefKind(Tag).(PrefixProvider),
+			view:        Raw,
+			path:        "main.go",
+			expectedURL: "https://codeberg.org/arbourd/git-open/raw/tag/main/main.go",
+		},
+		"codeberg history at a commit": {
+			p:           defaultProviders[3].WithRefKind(CommitRef).(PrefixProvider),
+			view:        History,
+			path:        "open",
+			expectedURL: "https://codeberg.org/arbourd/git-open/commits/commit/main/open",
+		},
+		"path template": {
+			p: PrefixProvider{
+				baseURL:      "https://git.example.dev",
+				commitPrefix: "commit",
+				rawPrefix:    "raw",
+				pathTemplate: "{base}/{repo}/src/{refkind}/{ref}/{path}",
+			},
+			view:        Raw,
+			path:        "main.go",
+			unsupported: true,
+		},
+		"unsupported view": {
+			p:           defaultProviders[3],
+			view:        Blame,
+			path:        "main.go",
+			unsupported: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			url, ok := c.p.ViewURL(c.view, repo, "main", c.path, c.lstart, c.lend)
+			if ok == c.unsupported {
+				t.Fatalf("unexpected support:\n\t(GOT): %#v\n\t(WNT): %#v", ok, !c.unsupported)
+			}
+			if url != c.expectedURL {
+				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", url, c.expectedURL)
+			}
+		})
+	}
+}
+
+func TestCompareURL(t *testing.T) {
+	cases := map[string]struct {
+		p           PrefixProvider
+		expectedURL string
+		unsupported bool
+	}{
+		"github": {
+			p:           defaultProviders[0],
+			expectedURL: "https://github.com/arbourd/git-open/compare/main...feature/a",
+		},
+		"gitlab": {
+			p:           defaultProviders[1],
+			expectedURL: "https://gitlab.com/arbourd/git-open/-/compare/main...feature/a",
+		},
+		"bitbucket is unsupported": {
+			p:           defaultProviders[2],
+			unsupported: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			url, ok := c.p.CompareURL(repo, "main", "feature/a")
+			if ok == c.unsupported {
+				t.Fatalf("unexpected support:\n\t(GOT): %#v\n\t(WNT): %#v", ok, !c.unsupported)
+			}
+			if url != c.expectedURL {
+				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", url, c.expectedURL)
+			}
+		})
+	}
+}
+
+func TestPullRequestURL(t *testing.T) {
+	cases := map[string]struct {
+		p           PrefixProvider
+		expectedURL string
+		unsupported bool
+	}{
+		"github": {
+			p:           defaultProviders[0],
+			expectedURL: "https://github.com/arbourd/git-open/pull/new/feature/a",
+		},
+		"gitlab": {
+			p:           defaultProviders[1],
+			expectedURL: "https://gitlab.com/arbourd/git-open/-/merge_requests/new?merge_request%5Bsource_branch%5D=feature%2Fa",
+		},
+		"bitbucket": {
+			p:           defaultProviders[2],
+			expectedURL: "https://bitbucket.org/arbourd/git-open/pull-requests/new?source=feature%2Fa",
+		},
+		"codeberg is unsupported": {
+			p:           defaultProviders[3],
+			unsupported: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			url, ok := c.p.PullRequestURL(repo, "feature/a")
+			if ok == c.unsupported {
+				t.Fatalf("unexpected support:\n\t(GOT): %#v\n\t(WNT): %#v", ok, !c.unsupported)
+			}
+			if url != c.expectedURL {
+				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", url, c.expectedURL)
+			}
+		})
+	}
+}
+
 func TestLineAnchor(t *testing.T) {
 	cases := map[string]struct {
-		p           Provider
+		p           PrefixProvider
 		start       int
 		end         int
 		expectedURL string
@@ -209,20 +725,32 @@ func TestLineAnchor(t *testing.T) {
 			end:         10,
 			expectedURL: "#L3-L10",
 		},
+		"azure devops single line": {
+			p:           defaultProviders[4],
+			start:       3,
+			end:         0,
+			expectedURL: "&line=3",
+		},
+		"azure devops range": {
+			p:           defaultProviders[4],
+			start:       3,
+			end:         10,
+			expectedURL: "&line=3&lineEnd=10",
+		},
 		"single-verb format single line": {
-			p:           Provider{lineFormat: "#line-%d"},
+			p:           PrefixProvider{lineFormat: "#line-%d"},
 			start:       3,
 			end:         0,
 			expectedURL: "#line-3",
 		},
 		"single-verb format ignores range end": {
-			p:           Provider{lineFormat: "#line-%d"},
+			p:           PrefixProvider{lineFormat: "#line-%d"},
 			start:       3,
 			end:         10,
 			expectedURL: "#line-3",
 		},
 		"empty line format": {
-			p:           Provider{},
+			p:           PrefixProvider{},
 			start:       3,
 			end:         0,
 			expectedURL: "",
@@ -247,7 +775,7 @@ func TestLineAnchor(t *testing.T) {
 
 func TestRootURL(t *testing.T) {
 	cases := map[string]struct {
-		p           Provider
+		p           PrefixProvider
 		expectedURL string
 	}{
 		"github": {
@@ -266,6 +794,10 @@ func TestRootURL(t *testing.T) {
 			p:           defaultProviders[3],
 			expectedURL: "https://codeberg.org/arbourd/git-open",
 		},
+		"azure devops": {
+			p:           defaultProviders[4],
+			expectedURL: "https://dev.azure.com/arbourd/git-open",
+		},
 	}
 
 	for name, c := range cases {
@@ -304,10 +836,10 @@ func TestFromConfig(t *testing.T) {
 
 	cases := map[string]struct {
 		config            []string
-		expectedProviders []Provider
+		expectedProviders []PrefixProvider
 	}{
 		"empty git config": {
-			expectedProviders: []Provider{},
+			expectedProviders: []PrefixProvider{},
 		},
 		"single provider": {
 			config: []string{
@@ -315,7 +847,7 @@ func TestFromConfig(t *testing.T) {
 				"open.https://my.domain.dev.pathprefix -/tree",
 				"open.https://my.domain.dev.lineformat L%l-L%l",
 			},
-			expectedProviders: []Provider{
+			expectedProviders: []PrefixProvider{
 				{baseURL: "https://my.domain.dev", commitPrefix: "-/commit", pathPrefix: "-/tree", rawLineFormat: "L%l-L%l", lineFormat: "#L%d", lineFormatRange: "#L%d-L%d"},
 			},
 		},
@@ -325,7 +857,7 @@ func TestFromConfig(t *testing.T) {
 				"open.https://my.domain.dev.pathprefix -/tree",
 				"open.https://my.domain.dev.lineformat #L%l-L%l",
 			},
-			expectedProviders: []Provider{
+			expectedProviders: []PrefixProvider{
 				{baseURL: "https://my.domain.dev", commitPrefix: "-/commit", pathPrefix: "-/tree", rawLineFormat: "#L%l-L%l", lineFormat: "#L%d", lineFormatRange: "#L%d-L%d"},
 			},
 		},
@@ -338,7 +870,7 @@ func TestFromConfig(t *testing.T) {
 				"open.https://git.example2.dev.pathprefix tree",
 				"open.https://git.example2.dev.lineformat L%l-L%l",
 			},
-			expectedProviders: []Provider{
+			expectedProviders: []PrefixProvider{
 				{baseURL: "https://git.example1.dev", commitPrefix: "-/commit", pathPrefix: "-/tree", rawLineFormat: "L%l-L%l", lineFormat: "#L%d", lineFormatRange: "#L%d-L%d"},
 				{baseURL: "https://git.example2.dev", commitPrefix: "commit", pathPrefix: "tree", rawLineFormat: "L%l-L%l", lineFormat: "#L%d", lineFormatRange: "#L%d-L%d"},
 			},
@@ -349,7 +881,7 @@ func TestFromConfig(t *testing.T) {
 				"open.https://git.internal.corp.com.pathprefix tree",
 				"open.https://git.internal.corp.com.lineformat L%l-L%l",
 			},
-			expectedProviders: []Provider{
+			expectedProviders: []PrefixProvider{
 				{baseURL: "https://git.internal.corp.com", commitPrefix: "commit", pathPrefix: "tree", rawLineFormat: "L%l-L%l", lineFormat: "#L%d", lineFormatRange: "#L%d-L%d"},
 			},
 		},
@@ -359,7 +891,7 @@ func TestFromConfig(t *testing.T) {
 				"open.https://git.example3.dev.pathprefix tree",
 				"open.https://git.example3.dev.lineformat line-%l",
 			},
-			expectedProviders: []Provider{
+			expectedProviders: []PrefixProvider{
 				{baseURL: "https://git.example3.dev", commitPrefix: "commit", pathPrefix: "tree", rawLineFormat: "line-%l", lineFormat: "#line-%d", lineFormatRange: ""},
 			},
 		},
@@ -369,7 +901,7 @@ func TestFromConfig(t *testing.T) {
 				"open.https://git.example4.dev.pathprefix tree",
 				"open.https://git.example4.dev.lineformat #line-%l:%l",
 			},
-			expectedProviders: []Provider{
+			expectedProviders: []PrefixProvider{
 				{baseURL: "https://git.example4.dev", commitPrefix: "commit", pathPrefix: "tree", rawLineFormat: "#line-%l:%l", lineFormat: "#line-%d", lineFormatRange: "#line-%d:%d"},
 			},
 		},
@@ -379,7 +911,7 @@ func TestFromConfig(t *testing.T) {
 				"open.not-a-url.pathprefix tree",
 				"open.not-a-url.lineformat L%l-L%l",
 			},
-			expectedProviders: []Provider{},
+			expectedProviders: []PrefixProvider{},
 		},
 		"non-web scheme": {
 			config: []string{
@@ -387,14 +919,14 @@ func TestFromConfig(t *testing.T) {
 				"open.ssh://git.example.dev.pathprefix tree",
 				"open.ssh://git.example.dev.lineformat L%l-L%l",
 			},
-			expectedProviders: []Provider{},
+			expectedProviders: []PrefixProvider{},
 		},
 		"empty line format is ignored but not dropped": {
 			config: []string{
 				"open.https://git.example6.dev.commitprefix commit",
 				"open.https://git.example6.dev.pathprefix tree",
 			},
-			expectedProviders: []Provider{
+			expectedProviders: []PrefixProvider{
 				{baseURL: "https://git.example6.dev", commitPrefix: "commit", pathPrefix: "tree", rawLineFormat: "", lineFormat: "", lineFormatRange: ""},
 			},
 		},
@@ -404,8 +936,8 @@ func TestFromConfig(t *testing.T) {
 				"open.https://git.example6.dev.pathprefix tree",
 				"open.https://git.example6.dev.lineformat #L%s",
 			},
-			expectedProviders: []Provider{
-				{baseURL: "https://git.example6.dev", commitPrefix: "commit", pathPrefix: "tree", rawLineFormat: "#L%s", lineFormat: "", lineFormatRange: ""},
+			expectedProviders: []PrefixProvider{
+				{baseURL: "https://git.example6.dev", commitPrefix: "commit", pathPrefix: "tree", rawLineFormat: "", lineFormat: "", lineFormatRange: ""},
 			},
 		},
 		"line format with too many verbs is ignored but not dropped": {
@@ -414,8 +946,280 @@ func TestFromConfig(t *testing.T) {
 				"open.https://git.example7.dev.pathprefix tree",
 				"open.https://git.example7.dev.lineformat #L%d-%d-%d",
 			},
-			expectedProviders: []Provider{
-				{baseURL: "https://git.example7.dev", commitPrefix: "commit", pathPrefix: "tree", rawLineFormat: "#L%d-%d-%d", lineFormat: "", lineFormatRange: ""},
+			expectedProviders: []PrefixProvider{
+				{baseURL: "https://git.example7.dev", commitPrefix: "commit", pathPrefix: "tree", rawLineFormat: "", lineFormat: "", lineFormatRange: ""},
+			},
+		},
+		"heading style and plain query": {
+			config: []string{
+				"open.https://git.example8.dev.commitprefix -/commit",
+				"open.https://git.example8.dev.pathprefix -/tree",
+				"open.https://git.example8.dev.headingstyle gitlab",
+				"open.https://git.example8.dev.plainquery ?plain=1",
+				"open.https://git.example8.dev.headingquery anchor",
+			},
+			expectedProviders: []PrefixProvider{
+				{baseURL: "https://git.example8.dev", commitPrefix: "-/commit", pathPrefix: "-/tree", headingStyle: "gitlab", plainQuery: "plain=1", headingQuery: "anchor"},
+			},
+		},
+		"view prefixes": {
+			config: []string{
+				"open.https://git.example10.dev.commitprefix commit",
+				"open.https://git.example10.dev.pathprefix tree",
+				"open.https://git.example10.dev.blameprefix blame",
+				"open.https://git.example10.dev.rawprefix raw",
+				"open.https://git.example10.dev.historyprefix commits",
+			},
+			expectedProviders: []PrefixProvider{
+				{baseURL: "https://git.example10.dev", commitPrefix: "commit", pathPrefix: "tree", blamePrefix: "blame", rawPrefix: "raw", historyPrefix: "commits"},
+			},
+		},
+		"templates without prefixes": {
+			config: []string{
+				"open.https://git.example11.dev.committemplate {base}/{repo}/commit/{commit}",
+				"open.https://git.example11.dev.pathtemplate {base}/{repo}?path={path:query}&version={ref:query}",
+			},
+			expectedProviders: []PrefixProvider{
+				{
+					baseURL:        "https://git.example11.dev",
+					commitTemplate: "{base}/{repo}/commit/{commit}",
+					pathTemplate:   "{base}/{repo}?path={path:query}&version={ref:query}",
+				},
+			},
+		},
+		"invalid template is dropped, falling back to the prefix": {
+			config: []string{
+				"open.https://git.example12.dev.commitprefix commit",
+				"open.https://git.example12.dev.pathprefix tree",
+				"open.https://git.example12.dev.pathtemplate {base}/{repo}/src/{commit}",
+			},
+			expectedProviders: []PrefixProvider{
+				{baseURL: "https://git.example12.dev", commitPrefix: "commit", pathPrefix: "tree"},
+			},
+		},
+		"invalid template without a prefix is skipped": {
+			config: []string{
+				"open.https://git.example13.dev.commitprefix commit",
+				"open.https://git.example13.dev.pathtemplate {base}/{repo}/src/{ref",
+			},
+			expectedProviders: []PrefixProvider{},
+		},
+		"built-in provider is merged over": {
+			config: []string{
+				"open.https://github.com.pathprefix blob",
+				"open.https://github.com.lineformat L%l",
+			},
+			expectedProviders: []PrefixProvider{
+				func() PrefixProvider {
+					p := defaultProviders[0]
+					p.pathPrefix, p.rawLineFormat, p.lineFormat, p.lineFormatRange = "blob", "L%l", "#L%d", ""
+					return p
+				}(),
+			},
+		},
+		"built-in provider with a trailing slash is merged over": {
+			config: []string{
+				"open.https://gitlab.com/.pathprefix -/blob",
+			},
+			expectedProviders: []PrefixProvider{
+				func() PrefixProvider {
+					p := defaultProviders[1]
+					p.pathPrefix = "-/blob"
+					return p
+				}(),
+			},
+		},
+		"built-in provider is replaced": {
+			config: []string{
+				"open.https://github.com.replace true",
+				"open.https://github.com.commitprefix commit",
+				"open.https://github.com.pathprefix blob",
+			},
+			expectedProviders: []PrefixProvider{
+				{baseURL: "https://github.com", commitPrefix: "commit", pathPrefix: "blob"},
+			},
+		},
+		"disabled providers are left out": {
+			config: []string{
+				"open.https://bitbucket.org.enabled false",
+				"open.https://git.example14.dev.commitprefix commit",
+				"open.https://git.example14.dev.pathprefix tree",
+				"open.https://git.example14.dev.enabled no",
+			},
+			expectedProviders: []PrefixProvider{},
+		},
+		"type inherits the built-in provider": {
+			config: []string{
+				"open.https://gitlab.example15.dev.type gitlab",
+			},
+			expectedProviders: []PrefixProvider{
+				func() PrefixProvider {
+					p := defaultProviders[1]
+					p.baseURL, p.hostAliases = "https://gitlab.example15.dev", nil
+					return p
+				}(),
+			},
+		},
+		"type with keys set": {
+			config: []string{
+				"open.https://gitea.example16.dev.type gitea",
+				"open.https://gitea.example16.dev.pathprefix src/branch",
+			},
+			expectedProviders: []PrefixProvider{
+				func() PrefixProvider {
+					p := defaultProviders[3]
+					p.baseURL, p.pathPrefix, p.hostAliases = "https://gitea.example16.dev", "src/branch", nil
+					return p
+				}(),
+			},
+		},
+		"unknown type is skipped": {
+			config: []string{
+				"open.https://git.example17.dev.type sourcehut",
+				"open.https://git.example17.dev.commitprefix commit",
+				"open.https://git.example17.dev.pathprefix tree",
+			},
+			expectedProviders: []PrefixProvider{},
+		},
+		"host aliases": {
+			config: []string{
+				"open.https://git.example18.dev.commitprefix commit",
+				"open.https://git.example18.dev.pathprefix tree",
+				"open.https://git.example18.dev.hostalias git-ssh.example18.dev",
+				"open.https://git.example18.dev.sshhost ssh.example18.dev:2222",
+			},
+			expectedProviders: []PrefixProvider{
+				{
+					baseURL:      "https://git.example18.dev",
+					hostAliases:  []string{"git-ssh.example18.dev", "ssh.example18.dev:2222"},
+					commitPrefix: "commit",
+					pathPrefix:   "tree",
+				},
+			},
+		},
+		"invalid host alias is ignored": {
+			config: []string{
+				"open.https://git.example19.dev.commitprefix commit",
+				"open.https://git.example19.dev.pathprefix tree",
+				"open.https://git.example19.dev.hostalias https://git-ssh.example19.dev",
+			},
+			expectedProviders: []PrefixProvider{
+				{baseURL: "https://git.example19.dev", commitPrefix: "commit", pathPrefix: "tree"},
+			},
+		},
+		"host aliases are added to the built-in provider": {
+			config: []string{
+				"open.https://github.com.hostalias github-ssh.example.dev",
+			},
+			expectedProviders: []PrefixProvider{
+				func() PrefixProvider {
+					p := defaultProviders[0]
+					p.hostAliases = []string{"ssh.github.com", "github-ssh.example.dev"}
+					return p
+				}(),
+			},
+		},
+		"wildcard host": {
+			config: []string{
+				"open.https://git.*.example20.dev.type gitea",
+			},
+			expectedProviders: []PrefixProvider{
+				func() PrefixProvider {
+					p := defaultProviders[3]
+					p.baseURL = "https://git.*.example20.dev"
+					p.hostPattern = regexp.MustCompile(`(?i)^git\.[^.]+\.example20\.dev$`)
+					return p
+				}(),
+			},
+		},
+		"host regexp": {
+			config: []string{
+				"open.https://git.example21.dev.commitprefix commit",
+				"open.https://git.example21.dev.pathprefix tree",
+				"open.https://git.example21.dev.hostregexp ^git[0-9]*\\.example21\\.dev$",
+			},
+			expectedProviders: []PrefixProvider{
+				{baseURL: "https://git.example21.dev", commitPrefix: "commit", pathPrefix: "tree", hostPattern: regexp.MustCompile(`^git[0-9]*\.example21\.dev$`)},
+			},
+		},
+		"invalid host regexp is skipped": {
+			config: []string{
+				"open.https://git.example22.dev.commitprefix commit",
+				"open.https://git.example22.dev.pathprefix tree",
+				"open.https://git.example22.dev.hostregexp ^git(",
+			},
+			expectedProviders: []PrefixProvider{},
+		},
+		"file prefix": {
+			config: []string{
+				"open.https://git.example25.dev.commitprefix commit",
+				"open.https://git.example25.dev.pathprefix tree",
+				"open.https://git.example25.dev.fileprefix blob",
+			},
+			expectedProviders: []PrefixProvider{
+				{baseURL: "https://git.example25.dev", commitPrefix: "commit", pathPrefix: "tree", filePrefix: "blob"},
+			},
+		},
+		"ref kind prefixes": {
+			config: []string{
+				"open.https://git.example26.dev.commitprefix commit",
+				"open.https://git.example26.dev.pathprefix src/branch",
+				"open.https://git.example26.dev.branchpathprefix src/branch",
+				"open.https://git.example26.dev.tagpathprefix src/tag",
+				"open.https://git.example26.dev.commitpathprefix src/commit",
+			},
+			expectedProviders: []PrefixProvider{
+				{
+					baseURL: "https://git.example26.dev", commitPrefix: "commit", pathPrefix: "src/branch",
+					branchPathPrefix: "src/branch", tagPathPrefix: "src/tag", commitPathPrefix: "src/commit",
+				},
+			},
+		},
+		"azure devops server type": {
+			config: []string{
+				"open.https://tfs.example27.dev/tfs.type azuredevops",
+			},
+			expectedProviders: []PrefixProvider{
+				func() PrefixProvider {
+					p := defaultProviders[4]
+					p.baseURL = "https://tfs.example27.dev/tfs"
+					return p
+				}(),
+			},
+		},
+		"local paths": {
+			config: []string{
+				"open.https://git.example23.dev.type gitlab",
+				"open.https://git.example23.dev.localpath " + filepath.Join(root, "mirrors", "example23"),
+			},
+			expectedProviders: []PrefixProvider{
+				func() PrefixProvider {
+					p := defaultProviders[1]
+					p.baseURL = "https://git.example23.dev"
+					p.hostAliases = nil
+					p.localPaths = []string{filepath.Join(root, "mirrors", "example23")}
+					return p
+				}(),
+			},
+		},
+		"relative local path is ignored": {
+			config: []string{
+				"open.https://git.example24.dev.commitprefix commit",
+				"open.https://git.example24.dev.pathprefix tree",
+				"open.https://git.example24.dev.localpath mirrors/example24",
+			},
+			expectedProviders: []PrefixProvider{
+				{baseURL: "https://git.example24.dev", commitPrefix: "commit", pathPrefix: "tree"},
+			},
+		},
+		"invalid heading style is ignored but not dropped": {
+			config: []string{
+				"open.https://git.example9.dev.commitprefix commit",
+				"open.https://git.example9.dev.pathprefix tree",
+				"open.https://git.example9.dev.headingstyle sourcehut",
+			},
+			expectedProviders: []PrefixProvider{
+				{baseURL: "https://git.example9.dev", commitPrefix: "commit", pathPrefix: "tree"},
 			},
 		},
 	}
@@ -438,8 +1242,9 @@ func TestFromConfig(t *testing.T) {
 			if len(p) != len(c.expectedProviders) {
 				t.Logf("unexpected number of providers\n\t(GOT): %#v\n\t(WNT): %#v", len(p), len(c.expectedProviders))
 			}
-			sortOpt := cmpopts.SortSlices(func(a, b Provider) bool { return a.baseURL < b.baseURL })
-			if !cmp.Equal(p, c.expectedProviders, sortOpt, cmp.AllowUnexported(Provider{})) {
+			sortOpt := cmpopts.SortSlices(func(a, b PrefixProvider) bool { return a.baseURL < b.baseURL })
+			ignoreSource := cmpopts.IgnoreFields(PrefixProvider{}, "source")
+			if !cmp.Equal(p, c.expectedProviders, sortOpt, ignoreSource, cmp.AllowUnexported(PrefixProvider{}), compareRegexps) {
 				t.Fatalf("unexpected providers:\n\t(GOT): %#v\n\t(WNT): %#v", p, c.expectedProviders)
 			}
 		})
@@ -456,27 +1261,17 @@ func TestFromConfigLocal(t *testing.T) {
 	f.Close()
 	t.Setenv("GIT_CONFIG_GLOBAL", globalConfig)
 
-	dir := t.TempDir()
-	run := func(args ...string) {
-		t.Helper()
-		cmd := exec.Command("git", args...)
-		cmd.Dir = dir
-		if out, err := cmd.CombinedOutput(); err != nil {
-			t.Fatalf("git %v: %v\n%s", args, err, out)
-		}
-	}
-	run("init")
-	run("config", "user.email", "test@example.com")
-	run("config", "user.name", "Test")
-	run("config", "open.https://local.example.dev.commitprefix", "commit")
-	run("config", "open.https://local.example.dev.pathprefix", "tree")
-	run("config", "open.https://local.example.dev.lineformat", "L%l-L%l")
+	dir := initRepo(t, "")
+	runGit(t, dir, "config", "open.https://local.example.dev.commitprefix", "commit")
+	runGit(t, dir, "config", "open.https://local.example.dev.pathprefix", "tree")
+	runGit(t, dir, "config", "open.https://local.example.dev.lineformat", "L%l-L%l")
 
 	t.Chdir(dir)
 
 	providers := fromConfig()
-	expected := []Provider{{
+	expected := []PrefixProvider{{
 		baseURL:         "https://local.example.dev",
+		source:          "git config (file:.git/config)",
 		commitPrefix:    "commit",
 		pathPrefix:      "tree",
 		rawLineFormat:   "L%l-L%l",
@@ -484,38 +1279,167 @@ func TestFromConfigLocal(t *testing.T) {
 		lineFormatRange: "#L%d-L%d",
 	}}
 
-	sortOpt := cmpopts.SortSlices(func(a, b Provider) bool { return a.baseURL < b.baseURL })
-	if !cmp.Equal(providers, expected, sortOpt, cmp.AllowUnexported(Provider{})) {
+	sortOpt := cmpopts.SortSlices(func(a, b PrefixProvider) bool { return a.baseURL < b.baseURL })
+	if !cmp.Equal(providers, expected, sortOpt, cmp.AllowUnexported(PrefixProvider{}), compareRegexps) {
 		t.Fatalf("unexpected providers:\n\t(GOT): %#v\n\t(WNT): %#v", providers, expected)
 	}
 }
 
-func TestEscapePath(t *testing.T) {
+func TestJoinURL(t *testing.T) {
 	cases := map[string]struct {
-		url         string
+		baseURL     string
+		parts       []string
 		expectedURL string
 	}{
 		"simple": {
-			url:         "https://github.com/arbourd/git-open",
+			baseURL:     "https://github.com",
+			parts:       []string{"arbourd/git-open"},
 			expectedURL: "https://github.com/arbourd/git-open",
 		},
 		"with spaces": {
-			url:         "https://github.com/arbourd/git-open/tree/main/file with a space.txt",
+			baseURL:     "https://github.com",
+			parts:       []string{"arbourd/git-open", "tree", "main", "file with a space.txt"},
 			expectedURL: "https://github.com/arbourd/git-open/tree/main/file%20with%20a%20space.txt",
 		},
 		"trailing slash": {
-			url:         "https://github.com/arbourd/git-open/",
+			baseURL:     "https://github.com/",
+			parts:       []string{"arbourd/git-open/"},
 			expectedURL: "https://github.com/arbourd/git-open",
 		},
+		"empty parts": {
+			baseURL:     "https://github.com",
+			parts:       []string{"arbourd/git-open", "tree", "main", ""},
+			expectedURL: "https://github.com/arbourd/git-open/tree/main",
+		},
 		"hash in branch": {
-			url:         "https://github.com/arbourd/git-open/tree/fix/#123",
+			baseURL:     "https://github.com",
+			parts:       []string{"arbourd/git-open", "tree", "fix/#123"},
 			expectedURL: "https://github.com/arbourd/git-open/tree/fix/%23123",
 		},
+		"percent, question mark and plus in branch": {
+			baseURL:     "https://github.com",
+			parts:       []string{"arbourd/git-open", "tree", "100%?a+b"},
+			expectedURL: "https://github.com/arbourd/git-open/tree/100%25%3Fa+b",
+		},
+		"unicode path": {
+			baseURL:     "https://github.com",
+			parts:       []string{"arbourd/git-open", "tree", "main", "docs/café.md"},
+			expectedURL: "https://github.com/arbourd/git-open/tree/main/docs/caf%C3%A9.md",
+		},
+		"base path is not escaped again": {
+			baseURL:     "https://git.example.dev/my%20forge",
+			parts:       []string{"team/repo"},
+			expectedURL: "https://git.example.dev/my%20forge/team/repo",
+		},
+		"port": {
+			baseURL:     "https://git.example.dev:8443",
+			parts:       []string{"team/repo"},
+			expectedURL: "https://git.example.dev:8443/team/repo",
+		},
+		"ipv6 host": {
+			baseURL:     "http://[::1]:3000",
+			parts:       []string{"team/repo"},
+			expectedURL: "http://[::1]:3000/team/repo",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			url := joinURL(c.baseURL, c.parts...)
+			if url != c.expectedURL {
+				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", url, c.expectedURL)
+			}
+		})
+	}
+}
+
+func TestURLEncoding(t *testing.T) {
+	refs := []string{"main", "feature/a#b", "100%", "what?", "a+b", "release/v1.0 beta", "café"}
+	paths := []string{"main.go", "docs/file with a space.txt", "docs/#notes.txt", "src/100%.go", "src/a+b?.go", "docs/café/日本語.go"}
+
+	for _, p := range defaultProviders {
+		for _, ref := range refs {
+			for _, path := range paths {
+				t.Run(p.baseURL+"/"+ref+"/"+path, func(t *testing.T) {
+					u, err := url.Parse(p.PathURL(repo, ref, path, 0, 0))
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					if u.Fragment != "" {
+						t.Fatalf("unexpected fragment:\n\t(GOT): %#v\n\t(WNT): none", u.Fragment)
+					}
+					if p.pathTemplate != "" {
+						// Query based providers, like Azure DevOps, put the ref and path in the query
+						query := u.Query()
+						if query.Get("path") != "/"+path || query.Get("version") != p.pathPrefix+ref {
+							t.Fatalf("unexpected query:\n\t(GOT): %#v\n\t(WNT): path %#v, version %#v", u.RawQuery, "/"+path, p.pathPrefix+ref)
+						}
+						return
+					}
+					if u.RawQuery != "" {
+						t.Fatalf("unexpected query:\n\t(GOT): %#v\n\t(WNT): none", u.RawQuery)
+					}
+					expectedPath := "/" + strings.Join([]string{repo, p.pathPrefix, ref, path}, "/")
+					if u.Path != expectedPath {
+						t.Fatalf("unexpected path:\n\t(GOT): %#v\n\t(WNT): %#v", u.Path, expectedPath)
+					}
+
+					for _, view := range Views {
+						viewURL, ok := p.ViewURL(view, repo, ref, path, 0, 0)
+						if !ok {
+							continue
+						}
+						if u, err := url.Parse(viewURL); err != nil || u.Fragment != "" || !strings.HasSuffix(u.Path, "/"+ref+"/"+path) {
+							t.Fatalf("unexpected %s url:\n\t(GOT): %#v\n\t(WNT): ending in %#v", view, viewURL, "/"+ref+"/"+path)
+						}
+					}
+				})
+			}
+
+			if compareURL, ok := p.CompareURL(repo, "main", ref); ok {
+				u, err := url.Parse(compareURL)
+				if err != nil || u.Fragment != "" || !strings.HasSuffix(u.Path, "/main..."+ref) {
+					t.Errorf("unexpected compare url:\n\t(GOT): %#v\n\t(WNT): ending in %#v", compareURL, "/main..."+ref)
+				}
+			}
+			if pullRequestURL, ok := p.PullRequestURL(repo, ref); ok {
+				u, err := url.Parse(pullRequestURL)
+				if err != nil || u.Fragment != "" || (!strings.HasSuffix(u.Path, "/"+ref) && u.Query().Get(p.pullRequestQuery) != ref) {
+					t.Errorf("unexpected pull request url:\n\t(GOT): %#v\n\t(WNT): with %#v", pullRequestURL, ref)
+				}
+			}
+		}
+	}
+}
+
+func TestURLEncodingByProvider(t *testing.T) {
+	ref, path := "feature/a#b", "docs/read me?.md"
+
+	cases := map[string]struct {
+		p           PrefixProvider
+		expectedURL string
+	}{
+		"github": {
+			p:           defaultProviders[0],
+			expectedURL: "https://github.com/arbourd/git-open/tree/feature/a%23b/docs/read%20me%3F.md",
+		},
+		"gitlab": {
+			p:           defaultProviders[1],
+			expectedURL: "https://gitlab.com/arbourd/git-open/-/tree/feature/a%23b/docs/read%20me%3F.md",
+		},
+		"bitbucket": {
+			p:           defaultProviders[2],
+			expectedURL: "https://bitbucket.org/arbourd/git-open/src/feature/a%23b/docs/read%20me%3F.md",
+		},
+		"codeberg": {
+			p:           defaultProviders[3],
+			expectedURL: "https://codeberg.org/arbourd/git-open/src/branch/feature/a%23b/docs/read%20me%3F.md",
+		},
 	}
 
 	for name, c := range cases {
 		t.Run(name, func(t *testing.T) {
-			url := escapePath(c.url)
+			url := c.p.PathURL(repo, ref, path, 0, 0)
 			if url != c.expectedURL {
 				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", url, c.expectedURL)
 			}
@@ -548,6 +1472,11 @@ func TestParseRawLineFormat(t *testing.T) {
 			expectedLineFormat:  "#L%d",
 			expectedRangeFormat: "#L%d-L%d",
 		},
+		"query parameters": {
+			rawLineFormat:       "&line=%l&lineEnd=%l",
+			expectedLineFormat:  "&line=%d",
+			expectedRangeFormat: "&line=%d&lineEnd=%d",
+		},
 		"no verbs": {
 			rawLineFormat: "L",
 		},
@@ -0,0 +1,210 @@
+package open
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// registry holds the Providers added with Register
+var registry struct {
+	sync.RWMutex
+	providers []Provider
+}
+
+// Register adds p to the Providers matched against remotes, after the built-in Providers and before
+// the Providers defined in git config. A registered Provider with the same base URL is replaced.
+func Register(p Provider) error {
+	if p == nil || p.BaseURL() == "" {
+		return errors.New("unable to register provider: missing base URL")
+	}
+	if pp, ok := p.(PrefixProvider); ok && pp.source == "" {
+		pp.source = "registered"
+		p = pp
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	i := slices.IndexFunc(registry.providers, func(r Provider) bool { return r.BaseURL() == p.BaseURL() })
+	if i == -1 {
+		registry.providers = append(registry.providers, p)
+	} else {
+		registry.providers[i] = p
+	}
+	return nil
+}
+
+// registered returns a copy of the registered Providers
+func registered() []Provider {
+	registry.RLock()
+	defer registry.RUnlock()
+	return slices.Clone(registry.providers)
+}
+
+// List returns the built-in Providers followed by the registered Providers and the Providers defined in git config,
+// in the order they are matched against remotes. Built-in Providers overridden in git config are replaced in place,
+// and those disabled are left out.
+func List() []Provider {
+	configProviders, _, disabled := loadConfig(context.Background(), "")
+	return matchOrder(configProviders, disabled)
+}
+
+// Lookup returns the first Provider of List whose base URL has the host, like `github.com`, or else the first
+// Provider whose host pattern or base URL matches it, with its base URL on the host
+func Lookup(host string) (Provider, bool) {
+	var patterns []Provider
+	for _, p := range List() {
+		if _, _, ok := hostPatternOf(p); ok {
+			patterns = append(patterns, p)
+			continue
+		}
+		if matchesHost(p, host) {
+			return p, true
+		}
+	}
+
+	hostname := (&url.URL{Host: host}).Hostname()
+	for _, p := range patterns {
+		if matchesHostPattern(p, hostname) {
+			return p.(PrefixProvider).withHost(hostname), true
+		}
+	}
+	return nil, false
+}
+
+// providers returns the Providers matched against remotes, with git config read from the repository at path,
+// the base URLs of the git config providers skipped because they are invalid, and of the providers disabled
+func providers(ctx context.Context, path string) (providers []Provider, invalid []string, disabled []string) {
+	configProviders, invalid, disabled := loadConfig(ctx, path)
+	return matchOrder(configProviders, disabled), invalid, disabled
+}
+
+// matchOrder returns the Providers in the order they are matched against remotes: the built-in Providers,
+// unless disabled or replaced by the git config provider with the same base URL, then the registered
+// Providers and the remaining git config providers
+func matchOrder(configProviders []PrefixProvider, disabled []string) []Provider {
+	var builtIn, rest []Provider
+	overridden := make(map[string]bool)
+	for _, p := range defaultProviders {
+		if slices.Contains(disabled, p.baseURL) {
+			continue
+		}
+		i := slices.IndexFunc(configProviders, func(c PrefixProvider) bool { return c.baseURL == p.baseURL })
+		if i == -1 {
+			builtIn = append(builtIn, p)
+			continue
+		}
+		builtIn = append(builtIn, configProviders[i])
+		overridden[p.baseURL] = true
+	}
+	for _, p := range configProviders {
+		if !overridden[p.baseURL] {
+			rest = append(rest, p)
+		}
+	}
+	return slices.Concat(builtIn, registered(), rest)
+}
+
+// matchesHost reports whether the base URL of the provider has the host, like `github.com`, with the port when set,
+// or the host is an alias of the provider
+func matchesHost(p Provider, host string) bool {
+	return sameHost(p.BaseURL(), host, host != (&url.URL{Host: host}).Hostname()) || isHostAlias(p, host)
+}
+
+// isHostAlias reports whether the hostname of host is a host alias of the provider, ignoring ports
+func isHostAlias(p Provider, host string) bool {
+	a, ok := p.(HostAliaser)
+	if !ok {
+		return false
+	}
+	hostname := (&url.URL{Host: host}).Hostname()
+	return slices.ContainsFunc(a.HostAliases(), func(alias string) bool {
+		return strings.EqualFold((&url.URL{Host: alias}).Hostname(), hostname)
+	})
+}
+
+// sameHost reports whether baseURL has the hostname of host, ignoring case, and the port of host when port is set.
+// Default ports match the scheme of baseURL, like 443 for https.
+func sameHost(baseURL, host string, port bool) bool {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return false
+	}
+	h := &url.URL{Scheme: u.Scheme, Host: host}
+	if !strings.EqualFold(u.Hostname(), h.Hostname()) {
+		return false
+	}
+	return !port || webPort(u) == webPort(h)
+}
+
+// webPort returns the port of u, or the default port of its scheme
+func webPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	switch u.Scheme {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// hostPatternOf returns the host pattern of p and how specific it is, the number of literal characters of a
+// wildcard host, or false when p matches hosts exactly
+func hostPatternOf(p Provider) (*regexp.Regexp, int, bool) {
+	pp, ok := p.(PrefixProvider)
+	if !ok || pp.hostPattern == nil {
+		return nil, 0, false
+	}
+	var specificity int
+	if u, err := url.Parse(pp.baseURL); err == nil && strings.Contains(u.Hostname(), "*") {
+		specificity = len(strings.ReplaceAll(u.Hostname(), "*", ""))
+	}
+	return pp.hostPattern, specificity, true
+}
+
+// matchesHostPattern reports whether the host pattern of p matches hostname, or hostname is the host of the base
+// URL of p, like `git.mydomain.dev` for a host regexp of `^git[0-9]+\.mydomain\.dev$`
+func matchesHostPattern(p Provider, hostname string) bool {
+	re, _, ok := hostPatternOf(p)
+	return ok && (re.MatchString(hostname) || sameHost(p.BaseURL(), hostname, false))
+}
+
+// matchProvider returns repo relative to the path of the base URL of p, and how specifically p matches the host
+// and repo of a remote, or an error describing why it does not match. Ports only match for web remotes, as
+// SSH remotes are served on a different port than the web, and host aliases match any port. The path of the
+// base URL, like `/gitlab`, prefixes repo, except for SSH remotes, or remotes on a host alias, of forges served
+// below a path, which match less specifically.
+func matchProvider(p Provider, host, repo string, web bool) (string, int, error) {
+	u, err := url.Parse(p.BaseURL())
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid base URL: %w", err)
+	}
+	switch {
+	case isHostAlias(p, host):
+		web = false
+	case !sameHost(p.BaseURL(), host, false):
+		return "", 0, fmt.Errorf("host %q does not match", u.Host)
+	case web && !sameHost(p.BaseURL(), host, true):
+		return "", 0, fmt.Errorf("port %q does not match", webPort(u))
+	}
+
+	basePath := strings.Trim(u.Path, "/")
+	switch {
+	case basePath == "":
+		return repo, 0, nil
+	case strings.HasPrefix(repo, basePath+"/"):
+		return strings.TrimPrefix(repo, basePath+"/"), len(basePath), nil
+	case !web:
+		return repo, -1, nil
+	}
+	return "", 0, fmt.Errorf("path %q does not match", "/"+basePath)
+}
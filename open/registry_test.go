@@ -0,0 +1,491 @@
+package open
+
+import (
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// resetRegistry removes the registered Providers when the test ends
+func resetRegistry(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		registry.Lock()
+		registry.providers = nil
+		registry.Unlock()
+	})
+}
+
+func TestRegister(t *testing.T) {
+	resetRegistry(t)
+
+	first, err := NewProvider(ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := NewProvider(ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commits", PathPrefix: "src"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := Register(nil); err == nil {
+		t.Fatal("expected error registering a nil Provider, got nil")
+	}
+	if err := Register(PrefixProvider{}); err == nil {
+		t.Fatal("expected error registering the zero PrefixProvider, got nil")
+	}
+	if err := Register(first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Register(second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	providers := registered()
+	if len(providers) != 1 {
+		t.Fatalf("unexpected number of providers:\n\t(GOT): %#v\n\t(WNT): %#v", len(providers), 1)
+	}
+	if p := providers[0].(PrefixProvider); p.commitPrefix != "commits" {
+		t.Fatalf("unexpected commit prefix:\n\t(GOT): %#v\n\t(WNT): %#v", p.commitPrefix, "commits")
+	}
+	if providers[0].Source() != "registered" {
+		t.Fatalf("unexpected source:\n\t(GOT): %#v\n\t(WNT): %#v", providers[0].Source(), "registered")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	resetRegistry(t)
+
+	for _, c := range []ProviderConfig{
+		{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree"},
+		{BaseURL: "https://gitlab.*.corp", Type: "gitlab"},
+		{BaseURL: "https://git.mydomain.dev", HostRegexp: `^git[0-9]+\.mydomain\.dev$`, Type: "github"},
+	} {
+		p, err := NewProvider(c)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := Register(p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	cases := map[string]struct {
+		host            string
+		expectedBaseURL string
+		expectedOK      bool
+	}{
+		"built-in": {
+			host:            "gitlab.com",
+			expectedBaseURL: "https://gitlab.com",
+			expectedOK:      true,
+		},
+		"registered": {
+			host:            "git.example.dev",
+			expectedBaseURL: "https://git.example.dev",
+			expectedOK:      true,
+		},
+		"wildcard host": {
+			host:            "gitlab.eu.corp",
+			expectedBaseURL: "https://gitlab.eu.corp",
+			expectedOK:      true,
+		},
+		"host regexp": {
+			host:            "git2.mydomain.dev",
+			expectedBaseURL: "https://git2.mydomain.dev",
+			expectedOK:      true,
+		},
+		"base host of a host regexp": {
+			host:            "git.mydomain.dev",
+			expectedBaseURL: "https://git.mydomain.dev",
+			expectedOK:      true,
+		},
+		"unknown": {
+			host:       "unknown.example.com",
+			expectedOK: false,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, ok := Lookup(c.host)
+			var baseURL string
+			if ok {
+				baseURL = p.BaseURL()
+			}
+			if ok != c.expectedOK || baseURL != c.expectedBaseURL {
+				t.Fatalf("unexpected provider:\n\t(GOT): %#v, %#v\n\t(WNT): %#v, %#v", baseURL, ok, c.expectedBaseURL, c.expectedOK)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	resetRegistry(t)
+
+	p, err := NewProvider(ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Register(p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	providers := List()
+	if len(providers) <= len(defaultProviders) {
+		t.Fatalf("unexpected number of providers: %d", len(providers))
+	}
+	for i, p := range defaultProviders {
+		if providers[i].BaseURL() != p.BaseURL() {
+			t.Fatalf("unexpected provider %d:\n\t(GOT): %#v\n\t(WNT): %#v", i, providers[i].BaseURL(), p.BaseURL())
+		}
+	}
+	if got := providers[len(defaultProviders)].BaseURL(); got != "https://git.example.dev" {
+		t.Fatalf("unexpected registered provider:\n\t(GOT): %#v\n\t(WNT): %#v", got, "https://git.example.dev")
+	}
+}
+
+func TestListOverrides(t *testing.T) {
+	resetRegistry(t)
+
+	globalConfig := filepath.Join(t.TempDir(), ".gitconfig")
+	t.Setenv("GIT_CONFIG_GLOBAL", globalConfig)
+	for _, kv := range [][]string{
+		{"open.https://github.com.pathprefix", "blob"},
+		{"open.https://bitbucket.org.enabled", "false"},
+		{"open.https://git.example.dev.commitprefix", "commit"},
+		{"open.https://git.example.dev.pathprefix", "tree"},
+	} {
+		if out, err := exec.Command("git", "config", "--file", globalConfig, kv[0], kv[1]).CombinedOutput(); err != nil {
+			t.Fatalf("git config: %v\n%s", err, out)
+		}
+	}
+
+	var got []string
+	for _, p := range List() {
+		got = append(got, p.BaseURL()+" "+p.Source())
+	}
+	expected := []string{
+		"https://github.com git config (file:" + globalConfig + "), over built-in",
+		"https://gitlab.com built-in",
+		"https://codeberg.org built-in",
+		"https://dev.azure.com built-in",
+		"https://git.example.dev git config (file:" + globalConfig + ")",
+	}
+	if !slices.Equal(got, expected) {
+		t.Fatalf("unexpected providers:\n\t(GOT): %#v\n\t(WNT): %#v", got, expected)
+	}
+
+	p, ok := Lookup("github.com")
+	if !ok {
+		t.Fatal("expected a provider for github.com")
+	}
+	if url := p.PathURL("arbourd/git-open", "main", "main.go", 3, 0); url != "https://github.com/arbourd/git-open/blob/main/main.go#L3" {
+		t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", url, "https://github.com/arbourd/git-open/blob/main/main.go#L3")
+	}
+	if _, ok := Lookup("bitbucket.org"); ok {
+		t.Fatal("expected no provider for the disabled bitbucket.org")
+	}
+}
+
+// queryProvider is a Provider whose paths are query parameters, like `https://git.example.dev/<repo>?path=<path>`
+type queryProvider struct{}
+
+func (queryProvider) BaseURL() string { return "https://git.example.dev" }
+func (queryProvider) Source() string  { return "test" }
+
+func (queryProvider) CommitURL(repo, commitSHA string) string {
+	return "https://git.example.dev/" + repo + "/commit/" + commitSHA
+}
+
+func (queryProvider) PathURL(repo, ref, path string, lstart, lend int) string {
+	return "https://git.example.dev/" + repo + "?" + url.Values{"path": {"/" + path}, "version": {ref}}.Encode()
+}
+
+func (queryProvider) RootURL(repo string) string {
+	return "https://git.example.dev/" + repo
+}
+
+func TestResolveRegisteredInterface(t *testing.T) {
+	resetRegistry(t)
+
+	dir := initRepo(t, "git@git.example.dev:team/repo.git")
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Usage\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(queryProvider{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := map[string]struct {
+		opts        Options
+		expectedURL string
+		wantErr     bool
+	}{
+		"root": {
+			opts:        Options{},
+			expectedURL: "https://git.example.dev/team/repo",
+		},
+		"path": {
+			opts:        Options{Target: "README.md"},
+			expectedURL: "https://git.example.dev/team/repo?path=%2FREADME.md&version=main",
+		},
+		"heading without HeadingLinker": {
+			opts:        Options{Target: "README.md#usage"},
+			expectedURL: "https://git.example.dev/team/repo?path=%2FREADME.md&version=main",
+		},
+		"view without Viewer": {
+			opts:    Options{Target: "README.md", View: Blame},
+			wantErr: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			c.opts.RepoDir = dir
+			res, err := Resolve(t.Context(), c.opts)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got URL %q", res.URL)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.URL != c.expectedURL {
+				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", res.URL, c.expectedURL)
+			}
+		})
+	}
+}
+
+func TestResolveRegistered(t *testing.T) {
+	resetRegistry(t)
+
+	dir := initRepo(t, "git@git.example.dev:team/repo.git")
+
+	p, err := NewProvider(ProviderConfig{BaseURL: "https://git.example.dev", CommitPrefix: "commit", PathPrefix: "tree"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Register(p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var b strings.Builder
+	res, err := Resolve(t.Context(), Options{RepoDir: dir, Target: "7605d91", Explain: &b})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.URL != "https://git.example.dev/team/repo/commit/7605d91" {
+		t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", res.URL, "https://git.example.dev/team/repo/commit/7605d91")
+	}
+	if expected := `provider: https://git.example.dev from registered, matches host "git.example.dev"`; !strings.Contains(b.String(), expected) {
+		t.Fatalf("unexpected explanation:\n\t(GOT): %s\n\t(WNT): contains %q", b.String(), expected)
+	}
+}
+
+func TestMatchProvider(t *testing.T) {
+	cases := map[string]struct {
+		baseURL             string
+		hostAliases         []string
+		host                string
+		repo                string
+		web                 bool
+		expectedRepo        string
+		expectedSpecificity int
+		wantErr             bool
+	}{
+		"host": {
+			baseURL:      "https://github.com",
+			host:         "github.com",
+			repo:         "arbourd/git-open",
+			web:          true,
+			expectedRepo: "arbourd/git-open",
+		},
+		"host ignores case": {
+			baseURL:      "https://GitHub.com",
+			host:         "github.com",
+			repo:         "arbourd/git-open",
+			expectedRepo: "arbourd/git-open",
+		},
+		"different host": {
+			baseURL: "https://gitlab.com",
+			host:    "github.com",
+			repo:    "arbourd/git-open",
+			wantErr: true,
+		},
+		"web port": {
+			baseURL:      "https://git.example.com:8443",
+			host:         "git.example.com:8443",
+			repo:         "team/repo",
+			web:          true,
+			expectedRepo: "team/repo",
+		},
+		"default web port": {
+			baseURL:      "https://git.example.com",
+			host:         "git.example.com:443",
+			repo:         "team/repo",
+			web:          true,
+			expectedRepo: "team/repo",
+		},
+		"different web port": {
+			baseURL: "https://git.example.com:8443",
+			host:    "git.example.com",
+			repo:    "team/repo",
+			web:     true,
+			wantErr: true,
+		},
+		"ssh port is not the web port": {
+			baseURL:      "https://git.example.com:8443",
+			host:         "git.example.com:2222",
+			repo:         "team/repo",
+			expectedRepo: "team/repo",
+		},
+		"base path": {
+			baseURL:             "https://code.example.com/gitlab/",
+			host:                "code.example.com",
+			repo:                "gitlab/team/repo",
+			web:                 true,
+			expectedRepo:        "team/repo",
+			expectedSpecificity: len("gitlab"),
+		},
+		"base path of ssh remote": {
+			baseURL:             "https://code.example.com/gitlab",
+			host:                "code.example.com",
+			repo:                "team/repo",
+			expectedRepo:        "team/repo",
+			expectedSpecificity: -1,
+		},
+		"host alias": {
+			baseURL:      "https://github.com",
+			hostAliases:  []string{"ssh.github.com"},
+			host:         "ssh.github.com:443",
+			repo:         "arbourd/git-open",
+			expectedRepo: "arbourd/git-open",
+		},
+		"web remote on a host alias": {
+			baseURL:             "https://code.example.com/gitlab",
+			hostAliases:         []string{"git.example.com"},
+			host:                "git.example.com",
+			repo:                "team/repo",
+			web:                 true,
+			expectedRepo:        "team/repo",
+			expectedSpecificity: -1,
+		},
+		"different host alias": {
+			baseURL:     "https://github.com",
+			hostAliases: []string{"ssh.github.com"},
+			host:        "altssh.gitlab.com",
+			repo:        "arbourd/git-open",
+			wantErr:     true,
+		},
+		"different base path": {
+			baseURL: "https://code.example.com/gitlab",
+			host:    "code.example.com",
+			repo:    "gitea/team/repo",
+			web:     true,
+			wantErr: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := PrefixProvider{baseURL: c.baseURL, hostAliases: c.hostAliases}
+			repo, specificity, err := matchProvider(p, c.host, c.repo, c.web)
+			if err != nil && !c.wantErr {
+				t.Fatalf("unexpected error:\n\t(GOT): %#v\n\t(WNT): nil", err)
+			} else if err == nil && c.wantErr {
+				t.Fatalf("expected error:\n\t(GOT): nil")
+			} else if repo != c.expectedRepo || specificity != c.expectedSpecificity {
+				t.Fatalf("unexpected match:\n\t(GOT): %#v, %#v\n\t(WNT): %#v, %#v", repo, specificity, c.expectedRepo, c.expectedSpecificity)
+			}
+		})
+	}
+}
+
+func TestResolveRemoteHosts(t *testing.T) {
+	resetRegistry(t)
+
+	for _, c := range []ProviderConfig{
+		{BaseURL: "https://code.example.com", CommitPrefix: "commit", PathPrefix: "tree"},
+		{BaseURL: "https://code.example.com/gitlab", Type: "gitlab"},
+		{BaseURL: "https://git.example.com:8443", Type: "gitea"},
+		{BaseURL: "https://*.corp", CommitPrefix: "commit", PathPrefix: "tree"},
+		{BaseURL: "https://gitlab.*.corp", Type: "gitlab"},
+		{BaseURL: "https://code-eu.example.com", HostRegexp: `^code-[a-z]+\.example\.com$`, Type: "github"},
+		{BaseURL: "https://git.mydomain.dev", HostRegexp: `^git[0-9]+\.mydomain\.dev$`, Type: "gitea"},
+	} {
+		p, err := NewProvider(c)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := Register(p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	cases := map[string]struct {
+		remote      string
+		expectedURL string
+	}{
+		"https remote below the base path": {
+			remote:      "https://code.example.com/gitlab/team/repo.git",
+			expectedURL: "https://code.example.com/gitlab/team/repo/-/commit/7605d91",
+		},
+		"https remote outside the base path": {
+			remote:      "https://code.example.com/team/repo.git",
+			expectedURL: "https://code.example.com/team/repo/commit/7605d91",
+		},
+		"ssh remote on a different port": {
+			remote:      "ssh://git@git.example.com:2222/team/repo.git",
+			expectedURL: "https://git.example.com:8443/team/repo/commit/7605d91",
+		},
+		"ssh remote on a built-in host alias": {
+			remote:      "ssh://git@ssh.github.com:443/arbourd/git-open.git",
+			expectedURL: "https://github.com/arbourd/git-open/commit/7605d91",
+		},
+		"remote on a wildcard host": {
+			remote:      "git@gitlab.eu.corp:team/repo.git",
+			expectedURL: "https://gitlab.eu.corp/team/repo/-/commit/7605d91",
+		},
+		"remote on a less specific wildcard host": {
+			remote:      "git@forge.corp:team/repo.git",
+			expectedURL: "https://forge.corp/team/repo/commit/7605d91",
+		},
+		"remote on a host regexp": {
+			remote:      "https://code-us.example.com/team/repo.git",
+			expectedURL: "https://code-us.example.com/team/repo/commit/7605d91",
+		},
+		"remote on the base host of a host regexp": {
+			remote:      "git@git.mydomain.dev:team/repo.git",
+			expectedURL: "https://git.mydomain.dev/team/repo/commit/7605d91",
+		},
+		"remote on a host regexp that excludes the base host": {
+			remote:      "git@git2.mydomain.dev:team/repo.git",
+			expectedURL: "https://git2.mydomain.dev/team/repo/commit/7605d91",
+		},
+		"https remote on the web port": {
+			remote:      "https://git.example.com:8443/team/repo.git",
+			expectedURL: "https://git.example.com:8443/team/repo/commit/7605d91",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := initRepo(t, c.remote)
+			res, err := Resolve(t.Context(), Options{RepoDir: dir, Target: "7605d91"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.URL != c.expectedURL {
+				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", res.URL, c.expectedURL)
+			}
+		})
+	}
+}
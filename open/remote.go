@@ -0,0 +1,174 @@
+package open
+
+import (
+	"cmp"
+	"context"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/arbourd/git-open/gitw"
+)
+
+// urlRewriteRegex matches the `url.<base>.insteadOf` and `url.<base>.pushInsteadOf` git config keys
+const urlRewriteRegex = `^url\..*\.(insteadof|pushinsteadof)$`
+
+// urlRewrite is a git config rule rewriting remote URLs that start with prefix to start with base instead
+type urlRewrite struct {
+	base   string
+	prefix string
+	push   bool
+}
+
+// key returns the git config key of the rewrite, like `url.git@github.com:.insteadOf`
+func (w urlRewrite) key() string {
+	if w.push {
+		return "url." + w.base + ".pushInsteadOf"
+	}
+	return "url." + w.base + ".insteadOf"
+}
+
+// loadURLRewrites returns the `insteadOf` and `pushInsteadOf` rules of git config, read from the repository at path
+func loadURLRewrites(ctx context.Context, path string) []urlRewrite {
+	out := gitw.ConfigGetRegexp(ctx, path, urlRewriteRegex)
+	if out == "" {
+		return nil
+	}
+
+	var rewrites []urlRewrite
+	for line := range strings.SplitSeq(out, "\n") {
+		key, prefix, _ := strings.Cut(line, " ")
+		rest, ok := strings.CutPrefix(key, "url.")
+		i := strings.LastIndex(rest, ".")
+		if !ok || i == -1 || prefix == "" {
+			continue
+		}
+		rewrites = append(rewrites, urlRewrite{
+			base:   rest[:i],
+			prefix: prefix,
+			push:   strings.EqualFold(rest[i+1:], "pushinsteadof"),
+		})
+	}
+	return rewrites
+}
+
+// rewriteURL applies the rewrite with the longest prefix of remote, like git. `insteadOf` rules are preferred,
+// then `pushInsteadOf` rules for remotes that are not URLs, like `corp:team/repo`, as they may only be written
+// for pushing. Returns false when none apply.
+func rewriteURL(remote string, rewrites []urlRewrite) (string, urlRewrite, bool) {
+	for _, push := range []bool{false, true} {
+		if push && strings.Contains(remote, "://") {
+			break
+		}
+		var best urlRewrite
+		for _, w := range rewrites {
+			if w.push == push && strings.HasPrefix(remote, w.prefix) && len(w.prefix) > len(best.prefix) {
+				best = w
+			}
+		}
+		if best.prefix != "" {
+			return best.base + strings.TrimPrefix(remote, best.prefix), best, true
+		}
+	}
+	return remote, urlRewrite{}, false
+}
+
+// normalizeRemoteHelper returns the URL of a remote using a git remote helper, or the remote unchanged:
+//
+//   - `persistent-https://host/repo` opens as `https://host/repo`
+//   - `sso://host/repo` opens as `https://host/repo`, with short hosts on `googlesource.com`
+//   - `codecommit::region://repo` and `codecommit://repo` open on the CodeCommit host of the region
+//   - other `transport::address` remotes open as their address
+func normalizeRemoteHelper(remote string) string {
+	if transport, address, ok := strings.Cut(remote, "::"); ok && !strings.Contains(transport, "/") {
+		if strings.EqualFold(transport, "codecommit") {
+			return codeCommitURL(address)
+		}
+		return address
+	}
+
+	scheme, rest, ok := strings.Cut(remote, "://")
+	if !ok {
+		return remote
+	}
+	switch strings.ToLower(scheme) {
+	case "persistent-https", "persistent-http":
+		return strings.TrimPrefix(strings.ToLower(scheme), "persistent-") + "://" + rest
+	case "sso":
+		host, repo, _ := strings.Cut(rest, "/")
+		if !strings.Contains(host, ".") {
+			host += ".googlesource.com"
+		}
+		return "https://" + host + "/" + repo
+	case "codecommit":
+		return codeCommitURL(rest)
+	}
+	return remote
+}
+
+// codeCommitURL returns the HTTPS URL of a git-remote-codecommit address, like `us-east-1://profile@repo`,
+// `repo` or `profile@repo`, in the region of the address or else of AWS_REGION or AWS_DEFAULT_REGION
+func codeCommitURL(address string) string {
+	region, repo, ok := strings.Cut(address, "://")
+	if !ok {
+		region, repo = "", address
+	}
+	if i := strings.LastIndex(repo, "@"); i != -1 {
+		repo = repo[i+1:]
+	}
+	if region == "" {
+		region = cmp.Or(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+	}
+
+	host := "git-codecommit.amazonaws.com"
+	if region != "" {
+		host = "git-codecommit." + region + ".amazonaws.com"
+	}
+	return "https://" + host + "/v1/repos/" + repo
+}
+
+// azureDevOpsURL returns the HTTPS URL on `dev.azure.com` of an Azure DevOps remote over SSH, like
+// `git@ssh.dev.azure.com:v3/org/project/repo`, or on a legacy `org.visualstudio.com` host, as
+// `https://dev.azure.com/org/project/_git/repo`. It returns false for other remotes.
+func azureDevOpsURL(remote string) (string, bool) {
+	host, repo, err := parseRepository(remote)
+	if err != nil {
+		return "", false
+	}
+
+	hostname := strings.ToLower((&url.URL{Host: host}).Hostname())
+	switch {
+	case hostname == "ssh.dev.azure.com" || hostname == "vs-ssh.visualstudio.com":
+		parts := strings.Split(repo, "/")
+		if len(parts) != 4 || parts[0] != "v3" {
+			return "", false
+		}
+		return joinURL("https://dev.azure.com", parts[1], parts[2], "_git", parts[3]), true
+	case strings.HasSuffix(hostname, ".visualstudio.com"):
+		org := strings.TrimSuffix(hostname, ".visualstudio.com")
+		if strings.Contains(org, ".") || !strings.Contains("/"+repo+"/", "/_git/") {
+			return "", false
+		}
+		repo, _ = cutPrefixFold(repo, "DefaultCollection/")
+		return joinURL("https://dev.azure.com", org, repo), true
+	}
+	return "", false
+}
+
+// normalizeRemote returns the remote with the `insteadOf` and `pushInsteadOf` rules of git config and its
+// remote helper applied, and Azure DevOps remotes on their web host, so it parses into a host and repository
+func (r *resolver) normalizeRemote(gitroot, remote string) string {
+	if rewritten, w, ok := rewriteURL(remote, loadURLRewrites(r.ctx, gitroot)); ok {
+		r.explainf("remote: %s, rewritten by %s = %s", redactURL(rewritten), w.key(), w.prefix)
+		remote = rewritten
+	}
+	if normalized := normalizeRemoteHelper(remote); normalized != remote {
+		r.explainf("remote: %s, from the remote helper of %s", redactURL(normalized), redactURL(remote))
+		remote = normalized
+	}
+	if web, ok := azureDevOpsURL(remote); ok {
+		r.explainf("remote: %s, the web remote of the Azure DevOps remote %s", web, redactURL(remote))
+		remote = web
+	}
+	return remote
+}
@@ -0,0 +1,231 @@
+package open
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestRewriteURL(t *testing.T) {
+	rewrites := []urlRewrite{
+		{base: "git@git.corp:", prefix: "corp:"},
+		{base: "git@git.corp:platform/", prefix: "corp:platform/"},
+		{base: "https://github.com/", prefix: "gh:"},
+		{base: "git@push.corp:", prefix: "pushcorp:", push: true},
+		{base: "ssh://git@github.com/", prefix: "https://github.com/", push: true},
+		{base: "git@git.corp:", prefix: "gh:", push: true},
+	}
+
+	cases := map[string]struct {
+		remote         string
+		expectedRemote string
+		expectedKey    string
+		expectedOK     bool
+	}{
+		"insteadOf": {
+			remote:         "corp:team/repo.git",
+			expectedRemote: "git@git.corp:team/repo.git",
+			expectedKey:    "url.git@git.corp:.insteadOf",
+			expectedOK:     true,
+		},
+		"longest prefix": {
+			remote:         "corp:platform/repo.git",
+			expectedRemote: "git@git.corp:platform/repo.git",
+			expectedKey:    "url.git@git.corp:platform/.insteadOf",
+			expectedOK:     true,
+		},
+		"insteadOf before pushInsteadOf": {
+			remote:         "gh:arbourd/git-open",
+			expectedRemote: "https://github.com/arbourd/git-open",
+			expectedKey:    "url.https://github.com/.insteadOf",
+			expectedOK:     true,
+		},
+		"pushInsteadOf": {
+			remote:         "pushcorp:team/repo.git",
+			expectedRemote: "git@push.corp:team/repo.git",
+			expectedKey:    "url.git@push.corp:.pushInsteadOf",
+			expectedOK:     true,
+		},
+		"pushInsteadOf does not apply to urls": {
+			remote:         "https://github.com/arbourd/git-open.git",
+			expectedRemote: "https://github.com/arbourd/git-open.git",
+		},
+		"no rewrite": {
+			remote:         "git@gitlab.com:team/repo.git",
+			expectedRemote: "git@gitlab.com:team/repo.git",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			remote, w, ok := rewriteURL(c.remote, rewrites)
+			var key string
+			if ok {
+				key = w.key()
+			}
+			if remote != c.expectedRemote || key != c.expectedKey || ok != c.expectedOK {
+				t.Fatalf("unexpected rewrite:\n\t(GOT): %#v, %#v, %#v\n\t(WNT): %#v, %#v, %#v", remote, key, ok, c.expectedRemote, c.expectedKey, c.expectedOK)
+			}
+		})
+	}
+}
+
+func TestNormalizeRemoteHelper(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "eu-west-1")
+
+	cases := map[string]struct {
+		remote         string
+		expectedRemote string
+	}{
+		"persistent-https": {
+			remote:         "persistent-https://go.googlesource.com/go",
+			expectedRemote: "https://go.googlesource.com/go",
+		},
+		"sso short host": {
+			remote:         "sso://chromium/chromium/src",
+			expectedRemote: "https://chromium.googlesource.com/chromium/src",
+		},
+		"sso host": {
+			remote:         "sso://git.corp.example.com/team/repo",
+			expectedRemote: "https://git.corp.example.com/team/repo",
+		},
+		"codecommit with region and profile": {
+			remote:         "codecommit::us-east-1://work@repo",
+			expectedRemote: "https://git-codecommit.us-east-1.amazonaws.com/v1/repos/repo",
+		},
+		"codecommit in the default region": {
+			remote:         "codecommit://repo",
+			expectedRemote: "https://git-codecommit.eu-west-1.amazonaws.com/v1/repos/repo",
+		},
+		"other transport": {
+			remote:         "ext::https://github.com/arbourd/git-open",
+			expectedRemote: "https://github.com/arbourd/git-open",
+		},
+		"https": {
+			remote:         "https://github.com/arbourd/git-open",
+			expectedRemote: "https://github.com/arbourd/git-open",
+		},
+		"scp-like": {
+			remote:         "git@github.com:arbourd/git-open.git",
+			expectedRemote: "git@github.com:arbourd/git-open.git",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if remote := normalizeRemoteHelper(c.remote); remote != c.expectedRemote {
+				t.Fatalf("unexpected remote:\n\t(GOT): %#v\n\t(WNT): %#v", remote, c.expectedRemote)
+			}
+		})
+	}
+}
+
+func TestAzureDevOpsURL(t *testing.T) {
+	cases := map[string]struct {
+		remote         string
+		expectedRemote string
+		expectedOK     bool
+	}{
+		"ssh": {
+			remote:         "git@ssh.dev.azure.com:v3/org/project/repo",
+			expectedRemote: "https://dev.azure.com/org/project/_git/repo",
+			expectedOK:     true,
+		},
+		"ssh url": {
+			remote:         "ssh://git@ssh.dev.azure.com/v3/org/project/repo",
+			expectedRemote: "https://dev.azure.com/org/project/_git/repo",
+			expectedOK:     true,
+		},
+		"legacy ssh": {
+			remote:         "org@vs-ssh.visualstudio.com:v3/org/project/repo",
+			expectedRemote: "https://dev.azure.com/org/project/_git/repo",
+			expectedOK:     true,
+		},
+		"legacy https": {
+			remote:         "https://org.visualstudio.com/project/_git/repo",
+			expectedRemote: "https://dev.azure.com/org/project/_git/repo",
+			expectedOK:     true,
+		},
+		"legacy https with the default collection": {
+			remote:         "https://org.visualstudio.com/DefaultCollection/project/_git/repo",
+			expectedRemote: "https://dev.azure.com/org/project/_git/repo",
+			expectedOK:     true,
+		},
+		"https": {
+			remote: "https://org@dev.azure.com/org/project/_git/repo",
+		},
+		"ssh without v3": {
+			remote: "git@ssh.dev.azure.com:org/project/repo",
+		},
+		"legacy https without a repository": {
+			remote: "https://org.visualstudio.com/project",
+		},
+		"github": {
+			remote: "git@github.com:arbourd/git-open.git",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			remote, ok := azureDevOpsURL(c.remote)
+			if remote != c.expectedRemote || ok != c.expectedOK {
+				t.Fatalf("unexpected remote:\n\t(GOT): %#v, %#v\n\t(WNT): %#v, %#v", remote, ok, c.expectedRemote, c.expectedOK)
+			}
+		})
+	}
+}
+
+func TestResolveRewrittenRemote(t *testing.T) {
+	cases := map[string]struct {
+		remote      string
+		config      []string
+		expectedURL string
+	}{
+		"insteadOf": {
+			remote:      "gh:arbourd/git-open",
+			config:      []string{"url.git@github.com:.insteadOf", "gh:"},
+			expectedURL: "https://github.com/arbourd/git-open/commit/7605d91",
+		},
+		"pushInsteadOf": {
+			remote:      "corp:team/repo.git",
+			config:      []string{"url.git@gitlab.com:.pushInsteadOf", "corp:"},
+			expectedURL: "https://gitlab.com/team/repo/-/commit/7605d91",
+		},
+		"remote helper": {
+			remote:      "persistent-https://codeberg.org/team/repo",
+			expectedURL: "https://codeberg.org/team/repo/commit/7605d91",
+		},
+		"azure devops ssh": {
+			remote:      "git@ssh.dev.azure.com:v3/org/project/repo",
+			expectedURL: "https://dev.azure.com/org/project/_git/repo/commit/7605d91",
+		},
+		"azure devops https": {
+			remote:      "https://org@dev.azure.com/org/project/_git/repo",
+			expectedURL: "https://dev.azure.com/org/project/_git/repo/commit/7605d91",
+		},
+		"azure devops legacy": {
+			remote:      "https://org.visualstudio.com/project/_git/repo",
+			expectedURL: "https://dev.azure.com/org/project/_git/repo/commit/7605d91",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := initRepo(t, c.remote)
+			if len(c.config) > 0 {
+				cmd := exec.Command("git", append([]string{"config"}, c.config...)...)
+				cmd.Dir = dir
+				if out, err := cmd.CombinedOutput(); err != nil {
+					t.Fatalf("git config: %v\n%s", err, out)
+				}
+			}
+			res, err := Resolve(t.Context(), Options{RepoDir: dir, Target: "7605d91"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.URL != c.expectedURL {
+				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", res.URL, c.expectedURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package open
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxSSHIncludeDepth limits nested Include directives, like ssh does
+const maxSSHIncludeDepth = 16
+
+// sshConfigPath returns the path of the ssh config read by Git, the file of the `-F` option of
+// GIT_SSH_COMMAND when set, or `~/.ssh/config`
+func sshConfigPath() string {
+	fields := strings.Fields(os.Getenv("GIT_SSH_COMMAND"))
+	for i, f := range fields {
+		switch {
+		case f == "-F" && i+1 < len(fields):
+			return expandHome(strings.Trim(fields[i+1], `"'`))
+		case strings.HasPrefix(f, "-F") && len(f) > 2:
+			return expandHome(strings.Trim(f[2:], `"'`))
+		}
+	}
+
+	if dir := sshUserDir(); dir != "" {
+		return filepath.Join(dir, "config")
+	}
+	return ""
+}
+
+// sshUserDir returns the directory of the user's ssh config, `~/.ssh`
+func sshUserDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh")
+}
+
+// sshHostName returns the HostName of the host alias in the ssh config at path, or false when it has none.
+// Like ssh, the first HostName of a matching Host block wins, Include directives are followed and
+// Match blocks, other than `Match all`, are skipped.
+func sshHostName(configPath, alias string) (string, bool) {
+	if configPath == "" {
+		return "", false
+	}
+	return readSSHConfig(configPath, alias, true, 0)
+}
+
+// readSSHConfig returns the HostName of the host alias in the ssh config file, where lines before the first
+// Host or Match block apply when active. Relative Include paths are in `~/.ssh`, like ssh.
+func readSSHConfig(file, alias string, active bool, depth int) (string, bool) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		keyword, args := splitSSHConfigLine(scanner.Text())
+		if len(args) == 0 {
+			continue
+		}
+
+		switch strings.ToLower(keyword) {
+		case "host":
+			active = matchSSHHost(args, alias)
+		case "match":
+			active = len(args) == 1 && strings.EqualFold(args[0], "all")
+		case "include":
+			if !active || depth >= maxSSHIncludeDepth {
+				continue
+			}
+			for _, pattern := range args {
+				pattern = expandHome(pattern)
+				if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join(sshUserDir(), pattern)
+				}
+				matches, _ := filepath.Glob(pattern)
+				for _, m := range matches {
+					if hostname, ok := readSSHConfig(m, alias, true, depth+1); ok {
+						return hostname, true
+					}
+				}
+			}
+		case "hostname":
+			if active {
+				return expandSSHTokens(args[0], alias), true
+			}
+		}
+	}
+	return "", false
+}
+
+// splitSSHConfigLine splits an ssh config line into its keyword and arguments, separated by whitespace
+// or `=`, with double quoted arguments kept whole
+func splitSSHConfigLine(line string) (string, []string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil
+	}
+
+	i := strings.IndexAny(line, " \t=")
+	if i == -1 {
+		return line, nil
+	}
+	keyword, rest := line[:i], strings.TrimSpace(line[i:])
+	rest = strings.TrimPrefix(rest, "=")
+
+	var args []string
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		if rest[0] == '"' {
+			arg, after, _ := strings.Cut(rest[1:], `"`)
+			args, rest = append(args, arg), after
+			continue
+		}
+		i := strings.IndexAny(rest, " \t")
+		if i == -1 {
+			args, rest = append(args, rest), ""
+			continue
+		}
+		args, rest = append(args, rest[:i]), rest[i:]
+	}
+	return keyword, args
+}
+
+// matchSSHHost reports whether the alias matches the patterns of a Host line, like `github-* !github-old`.
+// A negated pattern that matches excludes the alias.
+func matchSSHHost(patterns []string, alias string) bool {
+	var matched bool
+	for _, list := range patterns {
+		for p := range strings.SplitSeq(list, ",") {
+			negated := strings.HasPrefix(p, "!")
+			ok, _ := path.Match(strings.ToLower(strings.TrimPrefix(p, "!")), strings.ToLower(alias))
+			switch {
+			case ok && negated:
+				return false
+			case ok:
+				matched = true
+			}
+		}
+	}
+	return matched
+}
+
+// expandSSHTokens expands the `%h` and `%%` tokens of a HostName
+func expandSSHTokens(hostname, alias string) string {
+	return strings.NewReplacer("%h", alias, "%%", "%").Replace(hostname)
+}
+
+// expandHome expands a leading `~` to the home directory
+func expandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, p[1:])
+}
@@ -0,0 +1,192 @@
+package open
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSSHHostName(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	files := map[string]string{
+		filepath.Join(".ssh", "config"): `Include conf.d/*.conf
+
+# Work
+Host github-work
+    HostName github.com
+    User git
+
+Host gitlab-* !gitlab-old
+    HostName=gitlab.com
+
+Host *.internal
+    HostName %h.corp.example.com
+
+Match host bitbucket-work
+    HostName bitbucket.org
+`,
+		filepath.Join(".ssh", "conf.d", "team.conf"): `Host "team forge" team
+  HostName codeberg.org
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(home, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configPath := filepath.Join(home, ".ssh", "config")
+
+	cases := map[string]struct {
+		alias            string
+		expectedHostName string
+		expectedOK       bool
+	}{
+		"alias": {
+			alias:            "github-work",
+			expectedHostName: "github.com",
+			expectedOK:       true,
+		},
+		"wildcard": {
+			alias:            "gitlab-team",
+			expectedHostName: "gitlab.com",
+			expectedOK:       true,
+		},
+		"negated wildcard": {
+			alias:      "gitlab-old",
+			expectedOK: false,
+		},
+		"host token": {
+			alias:            "git.internal",
+			expectedHostName: "git.internal.corp.example.com",
+			expectedOK:       true,
+		},
+		"match blocks are skipped": {
+			alias:      "bitbucket-work",
+			expectedOK: false,
+		},
+		"include": {
+			alias:            "team",
+			expectedHostName: "codeberg.org",
+			expectedOK:       true,
+		},
+		"not an alias": {
+			alias:      "github.com",
+			expectedOK: false,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			hostname, ok := sshHostName(configPath, c.alias)
+			if ok != c.expectedOK || hostname != c.expectedHostName {
+				t.Fatalf("unexpected hostname:\n\t(GOT): %#v, %#v\n\t(WNT): %#v, %#v", hostname, ok, c.expectedHostName, c.expectedOK)
+			}
+		})
+	}
+}
+
+func TestSSHConfigPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	cases := map[string]struct {
+		sshCommand   string
+		expectedPath string
+	}{
+		"default": {
+			sshCommand:   "",
+			expectedPath: filepath.Join(home, ".ssh", "config"),
+		},
+		"without -F": {
+			sshCommand:   "ssh -i ~/.ssh/id_work",
+			expectedPath: filepath.Join(home, ".ssh", "config"),
+		},
+		"-F": {
+			sshCommand:   "ssh -F /etc/ssh/work_config -o IdentitiesOnly=yes",
+			expectedPath: "/etc/ssh/work_config",
+		},
+		"-F joined": {
+			sshCommand:   "ssh -F/etc/ssh/work_config",
+			expectedPath: "/etc/ssh/work_config",
+		},
+		"-F in home": {
+			sshCommand:   "ssh -F ~/work/ssh_config",
+			expectedPath: filepath.Join(home, "work", "ssh_config"),
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("GIT_SSH_COMMAND", c.sshCommand)
+			if path := sshConfigPath(); path != c.expectedPath {
+				t.Fatalf("unexpected path:\n\t(GOT): %#v\n\t(WNT): %#v", path, c.expectedPath)
+			}
+		})
+	}
+}
+
+func TestSplitSSHConfigLine(t *testing.T) {
+	cases := map[string]struct {
+		line            string
+		expectedKeyword string
+		expectedArgs    []string
+	}{
+		"space": {
+			line:            "  HostName github.com",
+			expectedKeyword: "HostName",
+			expectedArgs:    []string{"github.com"},
+		},
+		"equals": {
+			line:            "HostName = github.com",
+			expectedKeyword: "HostName",
+			expectedArgs:    []string{"github.com"},
+		},
+		"quoted": {
+			line:            "Host \"my forge\" forge\ttab",
+			expectedKeyword: "Host",
+			expectedArgs:    []string{"my forge", "forge", "tab"},
+		},
+		"comment": {
+			line: "# HostName github.com",
+		},
+		"empty": {
+			line: "   ",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			keyword, args := splitSSHConfigLine(c.line)
+			if keyword != c.expectedKeyword || !slices.Equal(args, c.expectedArgs) {
+				t.Fatalf("unexpected line:\n\t(GOT): %#v, %#v\n\t(WNT): %#v, %#v", keyword, args, c.expectedKeyword, c.expectedArgs)
+			}
+		})
+	}
+}
+
+func TestResolveSSHAlias(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "ssh_config")
+	if err := os.WriteFile(configPath, []byte("Host github-work\n  HostName github.com\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_SSH_COMMAND", "ssh -F "+configPath)
+
+	dir := initRepo(t, "git@github-work:arbourd/git-open.git")
+
+	res, err := Resolve(t.Context(), Options{RepoDir: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.URL != "https://github.com/arbourd/git-open" || res.Host != "github.com" {
+		t.Fatalf("unexpected result:\n\t(GOT): %#v, %#v\n\t(WNT): %#v, %#v", res.URL, res.Host, "https://github.com/arbourd/git-open", "github.com")
+	}
+}
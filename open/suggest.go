@@ -0,0 +1,106 @@
+package open
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/arbourd/git-open/gitw"
+)
+
+// pathNotFound returns an ErrPathNotFound error for path, with a hint naming the closest tracked path
+func (r *resolver) pathNotFound(p, gitroot string) error {
+	e := &Error{Err: fmt.Errorf("%w: %s", ErrPathNotFound, p)}
+	if s := r.suggestPath(p, gitroot); s != "" {
+		e.Hint = fmt.Sprintf("did you mean %q?", s)
+	}
+	return e
+}
+
+// suggestPath returns the tracked file or directory closest to path, relative to the resolver's directory,
+// or an empty string if none is close enough
+func (r *resolver) suggestPath(p, gitroot string) string {
+	rel, err := filepath.Rel(gitroot, r.abs(p))
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+
+	files, err := gitw.LsFiles(r.ctx, gitroot)
+	if err != nil {
+		return ""
+	}
+
+	match := closestPath(filepath.ToSlash(rel), trackedPaths(files))
+	if match == "" {
+		return ""
+	}
+
+	suggestion, err := filepath.Rel(r.dir, filepath.Join(gitroot, filepath.FromSlash(match)))
+	if err != nil {
+		return match
+	}
+	return suggestion
+}
+
+// trackedPaths returns the tracked files and every directory that contains them
+func trackedPaths(files []string) []string {
+	paths := slices.Clone(files)
+	seen := make(map[string]bool)
+	for _, f := range files {
+		for dir := path.Dir(f); dir != "." && !seen[dir]; dir = path.Dir(dir) {
+			seen[dir] = true
+			paths = append(paths, dir)
+		}
+	}
+	return paths
+}
+
+// closestPath returns the candidate with the smallest edit distance to want, comparing whole paths
+// and then base names, or an empty string if no candidate is within a third of the length of want
+func closestPath(want string, candidates []string) string {
+	if match := closest(want, candidates, func(s string) string { return s }); match != "" {
+		return match
+	}
+	return closest(path.Base(want), candidates, path.Base)
+}
+
+// closest returns the candidate whose key has the smallest edit distance to want,
+// or an empty string if no key is within a third of the length of want
+func closest(want string, candidates []string, key func(string) string) string {
+	want = strings.ToLower(want)
+
+	var match string
+	best := len([]rune(want))/3 + 1
+	for _, c := range candidates {
+		if d := levenshtein(want, strings.ToLower(key(c))); d < best {
+			match, best = c, d
+		}
+	}
+	return match
+}
+
+// levenshtein returns the number of single rune insertions, deletions or substitutions that turn a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,71 @@
+package open
+
+import (
+	"testing"
+)
+
+func TestClosestPath(t *testing.T) {
+	candidates := trackedPaths([]string{"main.go", "open/open.go", "open/provider.go", "README.md"})
+
+	cases := map[string]struct {
+		want     string
+		expected string
+	}{
+		"exact match": {
+			want:     "main.go",
+			expected: "main.go",
+		},
+		"transposed letters": {
+			want:     "open/opne.go",
+			expected: "open/open.go",
+		},
+		"case differs": {
+			want:     "readme.md",
+			expected: "README.md",
+		},
+		"directory": {
+			want:     "opn",
+			expected: "open",
+		},
+		"base name in another directory": {
+			want:     "provder.go",
+			expected: "open/provider.go",
+		},
+		"nothing close": {
+			want:     "completion.go",
+			expected: "",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			match := closestPath(c.want, candidates)
+			if match != c.expected {
+				t.Fatalf("unexpected match:\n\t(GOT): %#v\n\t(WNT): %#v", match, c.expected)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	cases := map[string]struct {
+		a, b     string
+		expected int
+	}{
+		"equal":         {a: "open", b: "open", expected: 0},
+		"empty":         {a: "", b: "open", expected: 4},
+		"substitution":  {a: "open", b: "opan", expected: 1},
+		"insertion":     {a: "opn", b: "open", expected: 1},
+		"transposition": {a: "opne", b: "open", expected: 2},
+		"unicode":       {a: "café", b: "cafe", expected: 1},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := levenshtein(c.a, c.b)
+			if d != c.expected {
+				t.Fatalf("unexpected distance:\n\t(GOT): %#v\n\t(WNT): %#v", d, c.expected)
+			}
+		})
+	}
+}
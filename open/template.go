@@ -0,0 +1,132 @@
+package open
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Template variables, written as `{name}` or `{name:escape}` in URL templates
+const (
+	varBase    = "base"
+	varRepo    = "repo"
+	varOwner   = "owner"
+	varName    = "name"
+	varCommit  = "commit"
+	varRef     = "ref"
+	varRefKind = "refkind"
+	varPrefix  = "prefix"
+	varPath    = "path"
+	varAnchor  = "anchor"
+)
+
+// Template escapes, selecting how a variable is escaped
+const (
+	// escapePathSegments escapes each `/` separated segment for a URL path, the default
+	escapePathSegments = "path"
+	// escapeQueryValue escapes the whole value, including `/`, for a URL query
+	escapeQueryValue = "query"
+	// escapeNone inserts the value as is
+	escapeNone = "raw"
+)
+
+// Variables allowed in each kind of template
+var (
+	rootTemplateVars   = []string{varBase, varRepo, varOwner, varName}
+	commitTemplateVars = []string{varBase, varRepo, varOwner, varName, varCommit}
+	pathTemplateVars   = []string{varBase, varRepo, varOwner, varName, varRef, varRefKind, varPrefix, varPath, varAnchor}
+	allTemplateVars    = append(slices.Clone(pathTemplateVars), varCommit)
+)
+
+// templatePart is a literal or a variable of a URL template
+type templatePart struct {
+	literal  string
+	variable string
+	escape   string
+}
+
+// parseTemplate parses a URL template like `{base}/{repo}/src/{ref}/{path}{anchor}`, with only the
+// variables allowed, or returns a validation error
+func parseTemplate(tmpl string, allowed []string) ([]templatePart, error) {
+	if tmpl == "" {
+		return nil, errors.New("empty template")
+	}
+
+	var parts []templatePart
+	for rest := tmpl; rest != ""; {
+		start := strings.IndexAny(rest, "{}")
+		if start == -1 {
+			parts = append(parts, templatePart{literal: rest})
+			break
+		}
+		if rest[start] == '}' {
+			return nil, fmt.Errorf("unexpected `}` in template: %q", tmpl)
+		}
+		if start > 0 {
+			parts = append(parts, templatePart{literal: rest[:start]})
+		}
+
+		end := strings.IndexAny(rest[start+1:], "{}")
+		if end == -1 || rest[start+1+end] == '{' {
+			return nil, fmt.Errorf("unclosed `{` in template: %q", tmpl)
+		}
+		variable, escape, _ := strings.Cut(rest[start+1:start+1+end], ":")
+		if !slices.Contains(allowed, variable) {
+			return nil, fmt.Errorf("unsupported variable in template: {%s}, expected one of: %s", variable, strings.Join(allowed, ", "))
+		}
+		switch escape {
+		case "":
+			escape = escapePathSegments
+		case escapePathSegments, escapeQueryValue, escapeNone:
+		default:
+			return nil, fmt.Errorf("unsupported escape in template: {%s:%s}, expected one of: %s, %s, %s",
+				variable, escape, escapePathSegments, escapeQueryValue, escapeNone)
+		}
+		parts = append(parts, templatePart{variable: variable, escape: escape})
+		rest = rest[start+1+end+1:]
+	}
+	return parts, nil
+}
+
+// validateTemplate returns a validation error if tmpl is not a URL template with only the variables allowed
+func validateTemplate(tmpl string, allowed []string) error {
+	_, err := parseTemplate(tmpl, allowed)
+	return err
+}
+
+// expandTemplate returns the URL of a validated template with its variables replaced by vars.
+// The base URL and anchor are never escaped, as they are URLs and fragments, or query parameters, already.
+func expandTemplate(tmpl string, vars map[string]string) string {
+	parts, _ := parseTemplate(tmpl, allTemplateVars)
+
+	var b strings.Builder
+	for _, part := range parts {
+		value := vars[part.variable]
+		switch {
+		case part.variable == "":
+			b.WriteString(part.literal)
+		case part.variable == varBase || part.variable == varAnchor || part.escape == escapeNone:
+			b.WriteString(value)
+		case part.escape == escapeQueryValue:
+			b.WriteString(url.QueryEscape(value))
+		default:
+			segments := strings.Split(value, "/")
+			for i, s := range segments {
+				segments[i] = url.PathEscape(s)
+			}
+			b.WriteString(strings.Join(segments, "/"))
+		}
+	}
+	return b.String()
+}
+
+// repoVars returns the template variables of a repository, like `owner/name`, hosted at baseURL
+func repoVars(baseURL, repo string) map[string]string {
+	owner, name := "", repo
+	if i := strings.LastIndex(repo, "/"); i != -1 {
+		owner, name = repo[:i], repo[i+1:]
+	}
+	return map[string]string{varBase: baseURL, varRepo: repo, varOwner: owner, varName: name}
+}
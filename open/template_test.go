@@ -0,0 +1,204 @@
+package open
+
+import (
+	"testing"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	cases := map[string]struct {
+		tmpl    string
+		allowed []string
+		wantErr bool
+	}{
+		"path template": {
+			tmpl:    "{base}/{repo}/src/{refkind}/{ref}/{path}{anchor}",
+			allowed: pathTemplateVars,
+		},
+		"escapes": {
+			tmpl:    "{base}/{owner:raw}/_git/{name}?path={path:query}&version=GB{ref:query}{anchor}",
+			allowed: pathTemplateVars,
+		},
+		"prefix": {
+			tmpl:    "{base}/{repo}?path=/{path:query}&version={prefix}{ref:query}{anchor}",
+			allowed: pathTemplateVars,
+		},
+		"prefix not allowed": {
+			tmpl:    "{base}/{repo}/{prefix}",
+			allowed: rootTemplateVars,
+			wantErr: true,
+		},
+		"literal only": {
+			tmpl:    "https://git.example.dev",
+			allowed: rootTemplateVars,
+		},
+		"empty": {
+			tmpl:    "",
+			allowed: rootTemplateVars,
+			wantErr: true,
+		},
+		"variable not allowed": {
+			tmpl:    "{base}/{repo}/commit/{path}",
+			allowed: commitTemplateVars,
+			wantErr: true,
+		},
+		"unknown variable": {
+			tmpl:    "{base}/{project}",
+			allowed: rootTemplateVars,
+			wantErr: true,
+		},
+		"line variable": {
+			tmpl:    "{base}/{repo}/src/{ref}/{path}#L{start}-{end}",
+			allowed: pathTemplateVars,
+			wantErr: true,
+		},
+		"unknown escape": {
+			tmpl:    "{base}/{repo:html}",
+			allowed: rootTemplateVars,
+			wantErr: true,
+		},
+		"unclosed": {
+			tmpl:    "{base}/{repo",
+			allowed: rootTemplateVars,
+			wantErr: true,
+		},
+		"nested": {
+			tmpl:    "{base}/{re{repo}}",
+			allowed: rootTemplateVars,
+			wantErr: true,
+		},
+		"unopened": {
+			tmpl:    "{base}/repo}",
+			allowed: rootTemplateVars,
+			wantErr: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := validateTemplate(c.tmpl, c.allowed)
+			if err != nil && !c.wantErr {
+				t.Fatalf("unexpected error:\n\t(GOT): %#v\n\t(WNT): nil", err)
+			} else if err == nil && c.wantErr {
+				t.Fatalf("expected error:\n\t(GOT): nil")
+			}
+		})
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	vars := repoVars("https://git.example.dev", "group/sub group/repo")
+	vars[varRef] = "feature/a+b"
+	vars[varPath] = "docs/read me.md"
+	vars[varAnchor] = "#L3"
+
+	cases := map[string]struct {
+		tmpl        string
+		expectedURL string
+	}{
+		"path segments": {
+			tmpl:        "{base}/{repo}/src/{ref}/{path}{anchor}",
+			expectedURL: "https://git.example.dev/group/sub%20group/repo/src/feature/a+b/docs/read%20me.md#L3",
+		},
+		"owner and name": {
+			tmpl:        "{base}/{owner}/_git/{name}",
+			expectedURL: "https://git.example.dev/group/sub%20group/_git/repo",
+		},
+		"query values": {
+			tmpl:        "{base}/{repo}?path=/{path:query}&version={ref:query}",
+			expectedURL: "https://git.example.dev/group/sub%20group/repo?path=/docs%2Fread+me.md&version=feature%2Fa%2Bb",
+		},
+		"raw": {
+			tmpl:        "{base}/{repo:raw}",
+			expectedURL: "https://git.example.dev/group/sub group/repo",
+		},
+		"unset variable": {
+			tmpl:        "{base}/{repo}/commit/{commit}",
+			expectedURL: "https://git.example.dev/group/sub%20group/repo/commit/",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			url := expandTemplate(c.tmpl, vars)
+			if url != c.expectedURL {
+				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", url, c.expectedURL)
+			}
+		})
+	}
+}
+
+func TestTemplateURLs(t *testing.T) {
+	p, err := NewProvider(ProviderConfig{
+		BaseURL:        "https://git.example.dev",
+		CommitTemplate: "{base}/{repo}/commit/{commit}",
+		PathTemplate:   "{base}/{repo}/src/{refkind}/{ref}/{path}{anchor}",
+		RootTemplate:   "{base}/{owner}/_git/{name}",
+		LineFormat:     "L%l-L%l",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tag := p.WithRefKind(Tag).(HeadingLinker)
+
+	query, err := NewProvider(ProviderConfig{
+		BaseURL:       "https://git.example.dev",
+		CommitPrefix:  "commit",
+		PathPrefix:    "GB",
+		TagPathPrefix: "GT",
+		PathTemplate:  "{base}/{repo}?path=/{path:query}&version={prefix}{ref:query}{anchor}",
+		LineFormat:    "&line=%l&lineEnd=%l",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := map[string]struct {
+		url         string
+		expectedURL string
+	}{
+		"commit": {
+			url:         p.CommitURL(repo, "7605d91"),
+			expectedURL: "https://git.example.dev/arbourd/git-open/commit/7605d91",
+		},
+		"path defaults to a branch": {
+			url:         p.PathURL(repo, "main", "main.go", 0, 0),
+			expectedURL: "https://git.example.dev/arbourd/git-open/src/branch/main/main.go",
+		},
+		"path with a line": {
+			url:         p.PathURL(repo, "main", "main.go", 3, 0),
+			expectedURL: "https://git.example.dev/arbourd/git-open/src/branch/main/main.go#L3",
+		},
+		"path with lines": {
+			url:         p.PathURL(repo, "main", "main.go", 3, 10),
+			expectedURL: "https://git.example.dev/arbourd/git-open/src/branch/main/main.go#L3-L10",
+		},
+		"heading at a tag": {
+			url:         tag.HeadingURL(repo, "v1.0.0", "README.md", "usage"),
+			expectedURL: "https://git.example.dev/arbourd/git-open/src/tag/v1.0.0/README.md#usage",
+		},
+		"query path without lines": {
+			url:         query.PathURL(repo, "main", "docs", 0, 0),
+			expectedURL: "https://git.example.dev/arbourd/git-open?path=/docs&version=GBmain",
+		},
+		"query path with lines": {
+			url:         query.PathURL(repo, "main", "a/b.go", 3, 10),
+			expectedURL: "https://git.example.dev/arbourd/git-open?path=/a%2Fb.go&version=GBmain&line=3&lineEnd=10",
+		},
+		"query path with the prefix of the ref kind": {
+			url:         query.WithRefKind(Tag).PathURL(repo, "v1.0.0", "a.go", 3, 0),
+			expectedURL: "https://git.example.dev/arbourd/git-open?path=/a.go&version=GTv1.0.0&line=3",
+		},
+		"root": {
+			url:         p.RootURL(repo),
+			expectedURL: "https://git.example.dev/arbourd/_git/git-open",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if c.url != c.expectedURL {
+				t.Fatalf("unexpected url:\n\t(GOT): %#v\n\t(WNT): %#v", c.url, c.expectedURL)
+			}
+		})
+	}
+}